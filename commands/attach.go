@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".bmp"}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [entry] [files]...",
+	Short: "Copy files into an entry's attachments and link them in its body",
+	Args:  cobra.MinimumNArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeEntries(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := db.USERDB.FindEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("Error finding entry: %v", err)
+		}
+		err = attachFiles(entry, args[1:])
+		if err != nil {
+			return fmt.Errorf("Error attaching files: %v", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
+
+func attachFiles(entry *db.Entry, files []string) error {
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", file)
+		}
+	}
+
+	before, err := utils.HashFile(entry.FilePath)
+	if err != nil {
+		return err
+	}
+	lines, err := utils.GetLines(entry.FilePath)
+	if err != nil {
+		return err
+	}
+
+	lines = append(lines, "")
+	for _, file := range files {
+		attachment, err := db.USERDB.AddAttachment(entry.ID, file)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, attachmentMarkdown(entry, attachment))
+		fmt.Printf("Attached %s to #%d\n", attachment.FileName, entry.ID)
+	}
+
+	if err := utils.WriteLines(entry.FilePath, lines); err != nil {
+		return err
+	}
+	return processEdit(entry.ID, before, false)
+}
+
+// Markdown linking to an attachment, shown inline for images
+func attachmentMarkdown(entry *db.Entry, attachment *db.Attachment) string {
+	link := attachmentLink(entry.FilePath, attachment.FilePath)
+	if strings.ContainsAny(link, " ()") {
+		link = "<" + link + ">"
+	}
+
+	markdown := fmt.Sprintf("[%s](%s)", attachment.FileName, link)
+	if utils.SliceStrContains(imageExtensions, strings.ToLower(filepath.Ext(attachment.FileName))) {
+		markdown = "!" + markdown
+	}
+	return markdown
+}
+
+// The path of an attachment relative to the entry file linking to it
+func attachmentLink(entryPath string, attachmentPath string) string {
+	rel, err := filepath.Rel(filepath.Dir(entryPath), attachmentPath)
+	if err != nil {
+		return attachmentPath
+	}
+	return rel
+}
+
+// Copy the attachments of a volume's originals into the volume
+// and point the links in its file at the copies.
+// Files the volume already has a copy of are not copied again.
+func carryAttachments(volume *db.Entry, originals []db.Entry) error {
+	existing, err := db.USERDB.Attachments(volume.ID)
+	if err != nil {
+		return err
+	}
+	copies := make(map[string]string)
+	for _, attachment := range existing {
+		if hash, err := utils.HashFile(attachment.FilePath); err == nil {
+			copies[hash] = attachment.FilePath
+		}
+	}
+
+	var replacements []string
+	for _, original := range originals {
+		attachments, err := db.USERDB.Attachments(original.ID)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			hash, err := utils.HashFile(attachment.FilePath)
+			if err != nil {
+				return err
+			}
+			copyPath, ok := copies[hash]
+			if !ok {
+				copied, err := db.USERDB.AddAttachment(volume.ID, attachment.FilePath)
+				if err != nil {
+					return err
+				}
+				copyPath = copied.FilePath
+				copies[hash] = copyPath
+			}
+			replacements = append(replacements,
+				attachmentLink(original.FilePath, attachment.FilePath),
+				attachmentLink(volume.FilePath, copyPath))
+		}
+	}
+	if len(replacements) == 0 {
+		return nil
+	}
+
+	lines, err := utils.GetLines(volume.FilePath)
+	if err != nil {
+		return err
+	}
+	replacer := strings.NewReplacer(replacements...)
+	for i, line := range lines {
+		lines[i] = replacer.Replace(line)
+	}
+	return utils.WriteLines(volume.FilePath, lines)
+}
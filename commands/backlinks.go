@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	"github.com/spf13/cobra"
+)
+
+var showBroken bool
+
+var backlinksCmd = &cobra.Command{
+	Use:               "backlinks [entry]",
+	Short:             "List the entries linking to an entry with [[id]] or [[name]]",
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if showBroken {
+			return listBrokenLinks()
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("Provide an entry id or name, or --broken")
+		}
+
+		entry, err := db.USERDB.FindEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("Error finding entry: %v", err)
+		}
+		backlinks, err := db.USERDB.Backlinks(entry.ID)
+		if err != nil {
+			return fmt.Errorf("Error getting backlinks: %v", err)
+		}
+
+		fmt.Printf("%sLinks to #%d %s%s\n", ui.Blue, entry.ID, entry.DisplayTitle(), ui.Reset)
+		if len(backlinks) == 0 {
+			fmt.Println("\t", "Nothing links here yet")
+		}
+		for _, source := range backlinks {
+			fmt.Printf("\t%s#%d%s %s | Created: %s\n", ui.Bold, source.ID, ui.Reset, source.DisplayTitle(), source.CreatedAt.Format("01-02-2006"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backlinksCmd)
+
+	// Flags
+	backlinksCmd.Flags().BoolVarP(&showBroken, "broken", "b", false, "List links that don't point to an existing entry")
+}
+
+func listBrokenLinks() error {
+	links, err := db.USERDB.BrokenLinks()
+	if err != nil {
+		return fmt.Errorf("Error getting broken links: %v", err)
+	}
+	if len(links) == 0 {
+		fmt.Println("No broken links")
+		return nil
+	}
+	for _, link := range links {
+		fmt.Printf("%s#%d%s %s -> %s[[%s]]%s\n", ui.Bold, link.Source.ID, ui.Reset, link.Source.DisplayTitle(), ui.Red, link.Target, ui.Reset)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/spf13/cobra"
+)
+
+// Options shared by the non-interactive delete and merge commands
+var (
+	batchTags []string
+	dryRun    bool
+	assumeYes bool
+)
+
+// Add the flags for picking entries by tag and confirming the change
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&batchTags, "tags", "t", nil, "Also take the entries with ALL of these tags, or a saved @search")
+	cmd.Flags().BoolVarP(&inclusive, "inclusive", "i", false, "Match entries with ANY of the --tags instead")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Also match tags nested under the --tags")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would happen without changing anything")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation")
+
+	cmd.RegisterFlagCompletionFunc("tags", completeFindArgs)
+}
+
+// Entry ids or names are required unless --tags picks the entries
+func batchArgs(cmd *cobra.Command, args []string) error {
+	if len(batchTags) == 0 {
+		return cobra.MinimumNArgs(1)(cmd, args)
+	}
+	return nil
+}
+
+// Get the entries named by ids or names along with the ones matching --tags,
+// oldest first and without repeats
+func batchEntries(refs []string) ([]db.Entry, error) {
+	var entries []db.Entry
+	seen := make(map[uint]bool)
+	for _, ref := range refs {
+		entry, err := db.USERDB.FindEntry(ref)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding entry: %v", err)
+		}
+		if !seen[entry.ID] {
+			seen[entry.ID] = true
+			entries = append(entries, *entry)
+		}
+	}
+
+	if len(batchTags) > 0 {
+		if err := prepareSearch(batchTags); err != nil {
+			return nil, err
+		}
+		if err := newSearch(); err != nil {
+			return nil, fmt.Errorf("Error searching entries: %v", err)
+		}
+		for _, entry := range searchResults {
+			if !seen[entry.ID] {
+				seen[entry.ID] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// One line per entry, as listed before deleting or merging
+func batchList(entries []db.Entry) string {
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("  #%d %s (%s)", entry.ID, entry.DisplayTitle(), entry.CreatedAt.Format("01/02/2006")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Ask before going ahead, unless --yes was given
+func confirmBatch(question string) bool {
+	if assumeYes {
+		return true
+	}
+	answer := strings.ToLower(askStdin(question + " [y/N] "))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion",
+	Short: "Generate or install shell completion scripts",
+}
+
+var completionInstallCmd = &cobra.Command{
+	Use:       "install [bash|zsh|fish]",
+	Short:     "Install the completion script for your shell",
+	Long:      "Install the completion script for the given shell, or the one in $SHELL when none is given",
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := filepath.Base(os.Getenv("SHELL"))
+		if len(args) > 0 {
+			shell = args[0]
+		}
+		err := installCompletion(shell)
+		if err != nil {
+			return fmt.Errorf("Error installing completion: %v", err)
+		}
+		return nil
+	},
+}
+
+// Script generators for each supported shell
+var completionScripts = map[string]func(w io.Writer) error{
+	"bash": func(w io.Writer) error { return rootCmd.GenBashCompletionV2(w, true) },
+	"zsh":  func(w io.Writer) error { return rootCmd.GenZshCompletion(w) },
+	"fish": func(w io.Writer) error { return rootCmd.GenFishCompletion(w, true) },
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	completionCmd.AddCommand(completionInstallCmd)
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		generate := completionScripts[shell]
+		completionCmd.AddCommand(&cobra.Command{
+			Use:   shell,
+			Short: "Print the completion script for " + shell,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return generate(os.Stdout)
+			},
+		})
+	}
+}
+
+// Complete tag names, most used first, leaving out tags already given
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Flags like --tags take comma separated lists
+	done := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		done = toComplete[:i+1]
+		args = append(args, strings.Split(toComplete[:i], ",")...)
+		toComplete = toComplete[i+1:]
+	}
+
+	names, err := db.USERDB.TagNames(toComplete)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, name := range names {
+		if !utils.SliceStrContains(args, name) {
+			completions = append(completions, done+name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// Complete entry ids, most recently updated first, with their titles as descriptions
+func completeEntries(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var entries []db.Entry
+	err := db.USERDB.DB.Where("CAST(id AS TEXT) LIKE ?", toComplete+"%").
+		Order("updated_at DESC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, entry := range entries {
+		id := strconv.FormatUint(uint64(entry.ID), 10)
+		if !utils.SliceStrContains(args, id) {
+			completions = append(completions, id+"\t"+entry.DisplayTitle())
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// Write the completion script where the shell picks it up automatically
+func installCompletion(shell string) error {
+	generate, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", shell)
+	}
+
+	home := os.Getenv("HOME")
+	var path string
+	switch shell {
+	case "bash":
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		path = filepath.Join(dataHome, "bash-completion", "completions", rootCmd.Name())
+	case "zsh":
+		path = filepath.Join(home, ".zfunc", "_"+rootCmd.Name())
+	case "fish":
+		path = filepath.Join(home, ".config", "fish", "completions", rootCmd.Name()+".fish")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create %s: %v", filepath.Dir(path), err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if err := generate(file); err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+
+	fmt.Println("Installed", shell, "completion:", path)
+	if shell == "zsh" {
+		fmt.Println("Make sure your ~/.zshrc has these lines before compinit:")
+		fmt.Println("\tfpath=(~/.zfunc $fpath)")
+		fmt.Println("\tautoload -Uz compinit && compinit")
+	}
+	fmt.Println("Restart your shell to start using it.")
+	return nil
+}
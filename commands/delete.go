@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:               "delete [entries]...",
+	Short:             "Delete entries by id, name or tags",
+	Args:              batchArgs,
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := batchEntries(args)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No entries to delete")
+			return nil
+		}
+
+		if dryRun {
+			fmt.Printf("Would delete %d entries:\n%s\n", len(entries), batchList(entries))
+			return nil
+		}
+		fmt.Printf("Deleting %d entries:\n%s\n", len(entries), batchList(entries))
+		if !confirmBatch("Delete them permanently?") {
+			return fmt.Errorf("Delete cancelled")
+		}
+
+		failed := 0
+		for i := range entries {
+			warning, err := deleteEntry(&entries[i])
+			if err != nil {
+				fmt.Printf("#%d: %v\n", entries[i].ID, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Deleted #%d %s\n", entries[i].ID, entries[i].DisplayTitle())
+			if warning != "" {
+				fmt.Println("Warning:", warning)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("Error deleting entries: %d of %d failed", failed, len(entries))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	// Flags
+	addBatchFlags(deleteCmd)
+}
@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/hooks"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+)
+
+// Longest title derived from an entry's body
+const maxTitleLength = 80
+
+var (
+	headingRegex   = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+	inlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+	wikiLinkRegex  = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|[^\[\]]*)?\]\]`)
+)
+
+// Open an entry in the editor and process its file once the editor closes.
+// New entries that are left empty are discarded.
+func openEntry(entry *db.Entry, startPos int, insertMode bool, isNew bool) error {
+	before, err := utils.HashFile(entry.FilePath)
+	if err != nil {
+		return fmt.Errorf("Error reading entry: %v", err)
+	}
+
+	utils.OpenInNvim(entry.FilePath, startPos, insertMode)
+
+	return processEdit(entry.ID, before, isNew)
+}
+
+// Refresh an entry's metadata from its file after editing
+func processEdit(id uint, before string, isNew bool) error {
+	entry, err := db.USERDB.GetEntry(id)
+	if err != nil {
+		return err
+	}
+
+	lines, err := utils.GetLines(entry.FilePath)
+	if err != nil {
+		return fmt.Errorf("Error reading entry: %v", err)
+	}
+	after, err := utils.HashFile(entry.FilePath)
+	if err != nil {
+		return fmt.Errorf("Error reading entry: %v", err)
+	}
+	content := entryContent(entry, lines)
+	content.Hash = after
+	edited := after != before
+
+	if isNew && (!edited || isBlank(bodyLines(lines))) {
+		if err := db.USERDB.DeleteEntry(entry.ID); err != nil {
+			return fmt.Errorf("Error discarding empty entry: %v", err)
+		}
+		fmt.Println("Discarded empty entry:", entry.FilePath)
+		return nil
+	}
+
+	if !edited && content.Hash == entry.ContentHash {
+		return nil
+	}
+	if err := db.USERDB.UpdateEntryContent(entry, content, edited); err != nil {
+		return fmt.Errorf("Error updating entry: %v", err)
+	}
+
+	event := hooks.PostEdit
+	if isNew {
+		event = hooks.PostNew
+	} else if !edited {
+		return nil
+	}
+	// Pick up inline tags added by the edit
+	entry, err = db.USERDB.GetEntry(id)
+	if err != nil {
+		return err
+	}
+	if err := hooks.Run(event, entry); err != nil {
+		fmt.Println("Warning:", err)
+	}
+	return nil
+}
+
+// Delete an entry and its file, running the delete hooks around it.
+// A failing pre-delete hook keeps the entry.
+// Returns a warning naming the entries whose links to it are now broken.
+func deleteEntry(entry *db.Entry) (string, error) {
+	if err := hooks.Run(hooks.PreDelete, entry); err != nil {
+		return "", fmt.Errorf("Delete aborted: %v", err)
+	}
+
+	backlinks, err := db.USERDB.Backlinks(entry.ID)
+	if err != nil {
+		return "", err
+	}
+
+	payload := hooks.NewPayload(hooks.PostDelete, entry)
+	if err := db.USERDB.DeleteEntry(entry.ID); err != nil {
+		return "", fmt.Errorf("Error deleting entry: %v", err)
+	}
+
+	if err := hooks.RunPayload(payload); err != nil {
+		fmt.Println("Warning:", err)
+	}
+
+	if len(backlinks) == 0 {
+		return "", nil
+	}
+	var sources []string
+	for _, source := range backlinks {
+		sources = append(sources, fmt.Sprintf("#%d %s", source.ID, source.DisplayTitle()))
+	}
+	return "Broken links in: " + strings.Join(sources, ", "), nil
+}
+
+func init() {
+	db.RegisterMigration("entry-content", backfillContent)
+}
+
+// Store the derived metadata of entries from before it was kept.
+// Entries whose file is missing are skipped, editing them fills it in.
+func backfillContent(c *db.DatabaseClient) error {
+	entries, err := c.EntriesWithoutContent()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		lines, err := utils.GetLines(entry.FilePath)
+		if err != nil {
+			continue
+		}
+		hash, err := utils.HashFile(entry.FilePath)
+		if err != nil {
+			continue
+		}
+		content := entryContent(entry, lines)
+		content.Hash = hash
+		if err := c.UpdateEntryContent(entry, content, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Derive an entry's metadata from the lines of its file
+func entryContent(entry *db.Entry, lines []string) db.EntryContent {
+	body := bodyLines(lines)
+	content := db.EntryContent{
+		Title:      deriveTitle(body),
+		WordCount:  utils.CountWords(body),
+		InlineTags: inlineTags(body),
+		Links:      wikiLinks(body),
+	}
+	// Volumes are named by the user
+	if entry.IsVolume {
+		content.Title = entry.Name
+	}
+	return content
+}
+
+// Use the first heading, or else the first non-empty line, as a title.
+// Quotes, like the prompt of a prompted entry, are never the title.
+func deriveTitle(body []string) string {
+	title := ""
+	for _, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if match := headingRegex.FindStringSubmatch(trimmed); match != nil {
+			title = strings.TrimSpace(match[1])
+			break
+		}
+		if title == "" && trimmed != "" && trimmed != "---" && !strings.HasPrefix(trimmed, ">") {
+			title = trimmed
+		}
+	}
+
+	runes := []rune(title)
+	if len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength-3]) + "..."
+	}
+	return title
+}
+
+// Get the #tags written in a body, skipping fenced code blocks
+func inlineTags(body []string) []string {
+	var tags []string
+	inCode := false
+	for _, line := range body {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		for _, match := range inlineTagRegex.FindAllStringSubmatch(line, -1) {
+			tag := strings.TrimRight(match[1], "/-")
+			if tag != "" && !utils.SliceStrContains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// Get the targets of [[id]], [[name]] and [[name|label]] links in a body
+func wikiLinks(body []string) []string {
+	var targets []string
+	for _, line := range body {
+		for _, match := range wikiLinkRegex.FindAllStringSubmatch(line, -1) {
+			target := strings.TrimSpace(match[1])
+			if target != "" && !utils.SliceStrContains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+func isBlank(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Get the lines of an entry file after its date header
+func readBody(filePath string) ([]string, error) {
+	lines, err := utils.GetLines(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return bodyLines(lines), nil
+}
+
+func bodyLines(lines []string) []string {
+	start := config.CONFIG.START_POS - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > len(lines) {
+		return nil
+	}
+	return lines[start:]
+}
+
+// An entry as printed by --json
+type entryJSON struct {
+	ID         uint              `json:"id"`
+	Title      string            `json:"title"`
+	Name       string            `json:"name"`
+	Path       string            `json:"path"`
+	Tags       []string          `json:"tags"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Originals  []uint            `json:"originals,omitempty"`
+	WordCount  int               `json:"word_count"`
+	Pinned     bool              `json:"pinned"`
+	Starred    bool              `json:"starred"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func toEntryJSON(entry db.Entry) entryJSON {
+	out := entryJSON{
+		ID:        entry.ID,
+		Title:     entry.DisplayTitle(),
+		Name:      entry.Name,
+		Path:      entry.FilePath,
+		Tags:      []string{},
+		WordCount: entry.WordCount,
+		Pinned:    entry.Pinned,
+		Starred:   entry.Starred,
+		CreatedAt: entry.CreatedAt,
+		UpdatedAt: entry.UpdatedAt,
+	}
+	for _, tag := range entry.Tags {
+		out.Tags = append(out.Tags, tag.TagName)
+	}
+	for _, property := range entry.Properties {
+		if out.Properties == nil {
+			out.Properties = make(map[string]string)
+		}
+		out.Properties[property.Key] = property.Value
+	}
+	for _, original := range entry.Originals {
+		out.Originals = append(out.Originals, original.ID)
+	}
+	return out
+}
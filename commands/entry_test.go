@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeriveTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		body []string
+		want string
+	}{
+		{"first line", []string{"", "Went for a walk", "It rained"}, "Went for a walk"},
+		{"heading wins", []string{"Intro line", "## The Walk", "text"}, "The Walk"},
+		{"skips rule", []string{"---", "", "After the rule"}, "After the rule"},
+		{"skips prompt", []string{"> What are you grateful for?", "", "Good coffee"}, "Good coffee"},
+		{"prompt then heading", []string{"> What are you grateful for?", "# Thanks"}, "Thanks"},
+		{"only a quote", []string{"> Just a quote"}, ""},
+		{"empty", []string{"", "  "}, ""},
+		{"long", []string{strings.Repeat("a", maxTitleLength+10)}, strings.Repeat("a", maxTitleLength-3) + "..."},
+	}
+	for _, test := range tests {
+		if got := deriveTitle(test.body); got != test.want {
+			t.Errorf("%s: deriveTitle() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package commands
 
 import (
-	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chzyer/readline"
 	"github.com/projectz-ro/journalz-ro/config"
 	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/hooks"
 	"github.com/projectz-ro/journalz-ro/ui"
 	utils "github.com/projectz-ro/journalz-ro/zro_utils"
 	"github.com/spf13/cobra"
@@ -22,27 +24,71 @@ var (
 	ascending     bool
 	descending    bool
 	originalsOnly bool
+	recursive     bool
+	starredOnly   bool
+	findJSON      bool
+	whereExpr     string
+	sinceExpr     string
+	untilExpr     string
+	saveName      string
 
-	searchResults []db.Entry
-	searchTags    []string
-	mergeList     []db.Entry
+	searchResults   []db.Entry
+	searchTags      []string
+	whereConditions []propertyCondition
+	sinceTime       time.Time
+	untilTime       time.Time
+	mergeList       []db.Entry
+
+	// The flags' where, since and until with those of any saved searches added
+	searchWhere string
+	searchSince string
+	searchUntil string
+
+	// How createVolume splits the merge list into sections: "", "day" or "tag"
+	volumeGroupBy string
 )
 
 var findCmd = &cobra.Command{
-	Use:   "find [tags]",
+	Use:   "find [tags|@search]",
 	Short: "Find entries by tags",
-	Args:  cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if whereExpr == "" && sinceExpr == "" && untilExpr == "" {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return nil
+	},
+	ValidArgsFunction: completeFindArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		searchTags = args
-		for _, x := range searchTags {
-			x = strings.TrimSpace(strings.ToLower(x))
+		err := prepareSearch(args)
+		if err != nil {
+			return err
 		}
-		err := newSearch()
+
+		err = newSearch()
 		if err != nil {
 			return fmt.Errorf("Error initiating search: %v", err)
 		}
-		promptLoop()
-		return nil
+
+		msg := ""
+		if saveName != "" {
+			if err := db.USERDB.SaveSearch(currentSearch(saveName)); err != nil {
+				return fmt.Errorf("Error saving search: %v", err)
+			}
+			msg = "Saved search @" + strings.ToLower(strings.TrimPrefix(saveName, "@"))
+		}
+		if findJSON {
+			results := []entryJSON{}
+			for _, entry := range searchResults {
+				results = append(results, toEntryJSON(entry))
+			}
+			out, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return fmt.Errorf("Error encoding results: %v", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		return promptLoop(msg)
 	},
 }
 
@@ -55,6 +101,16 @@ func init() {
 	findCmd.PersistentFlags().BoolVarP(&ascending, "ascending", "a", false, "Sort by date/time in ascending order")
 	findCmd.PersistentFlags().BoolVarP(&descending, "descending", "d", false, "Sort by date/time in descending order")
 	findCmd.PersistentFlags().BoolVarP(&originalsOnly, "originals-only", "o", false, "Show only original entries (exclude volumes)")
+	findCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "r", false, "Also match tags nested under the provided tags (work matches work/projectx)")
+	findCmd.PersistentFlags().BoolVarP(&starredOnly, "starred", "s", false, "Show only starred entries")
+	findCmd.PersistentFlags().BoolVarP(&findJSON, "json", "j", false, "Print the results as JSON instead of prompting")
+	findCmd.PersistentFlags().StringVarP(&whereExpr, "where", "w", "", "Only show entries whose properties match, e.g. 'mood>=4 and project=apollo'")
+	findCmd.PersistentFlags().StringVar(&sinceExpr, "since", "", "Only show entries created on or after a date (YYYY-MM-DD, today, yesterday, 7d, 2w, 3m, 1y)")
+	findCmd.PersistentFlags().StringVar(&untilExpr, "until", "", "Only show entries created on or before a date")
+	findCmd.PersistentFlags().StringVar(&saveName, "save", "", "Save this search under a name to run again with find @name")
+
+	// Completion
+	findCmd.RegisterFlagCompletionFunc("save", completeSearches)
 }
 
 func newSearch() error {
@@ -71,19 +127,45 @@ func newSearch() error {
 }
 
 func initialSearch() error {
-	err := db.USERDB.DB.Preload("Tags").Where("EXISTS (?)", db.USERDB.DB.Table("entry_tags").
-		Select("entry_id").
-		Joins("JOIN tags ON entry_tags.tag_id = tags.id").
-		Where("tags.tag_name IN (?)", searchTags)).
-		Find(&searchResults).Error
+	searchTags = db.USERDB.ResolveAliases(searchTags)
+	tags := searchTags
+	if recursive {
+		for _, tag := range searchTags {
+			descendants, err := db.USERDB.DescendantTags(tag)
+			if err != nil {
+				return err
+			}
+			tags = append(tags, descendants...)
+		}
+	}
+
+	query := db.USERDB.DB.Preload("Tags").Preload("Originals").Preload("Properties")
+	// A --where filter or date range on its own searches every entry
+	if len(tags) > 0 {
+		query = query.Where("id IN (?)", db.USERDB.DB.Table("entry_tags").
+			Select("entry_id").
+			Joins("JOIN tags ON entry_tags.tag_id = tags.id").
+			Where("tags.tag_name IN (?)", tags))
+	}
+	err := query.Find(&searchResults).Error
 	if err != nil {
 		return fmt.Errorf("failed to search entries by tags: %v", err)
 	}
 	return nil
 }
 
+// Check if an entry's tag satisfies a search tag.
+// With recursive, tags nested below the search tag count too.
+func tagMatches(searchTag string, tag string) bool {
+	if searchTag == tag {
+		return true
+	}
+	return recursive && strings.HasPrefix(tag, searchTag+db.TagSeparator)
+}
+
 func refineSearch() error {
 	var results []db.Entry
+	searchTags = db.USERDB.ResolveAliases(searchTags)
 
 	if !inclusive {
 		tagSet := make(map[string]bool)
@@ -95,7 +177,7 @@ func refineSearch() error {
 			remaining := len(searchTags)
 			for _, tag := range searchTags {
 				for _, etag := range entry.Tags {
-					if tag == etag.TagName {
+					if tagMatches(tag, etag.TagName) {
 						remaining = remaining - 1
 						break
 					}
@@ -109,17 +191,43 @@ func refineSearch() error {
 		results = append(results, searchResults...)
 	}
 
+	if len(whereConditions) > 0 {
+		var filtered []db.Entry
+		for _, res := range results {
+			if matchesWhere(res.Properties, whereConditions) {
+				filtered = append(filtered, res)
+			}
+		}
+		results = filtered
+	}
+
 	if originalsOnly {
 		var filtered []db.Entry
 		for _, res := range results {
-			if len(res.Originals) == 0 {
-				filtered = append(results, res)
+			if !res.IsVolume {
+				filtered = append(filtered, res)
+			}
+		}
+		results = filtered
+	}
+	if !sinceTime.IsZero() || !untilTime.IsZero() {
+		var filtered []db.Entry
+		for _, res := range results {
+			if (sinceTime.IsZero() || !res.CreatedAt.Before(sinceTime)) &&
+				(untilTime.IsZero() || !res.CreatedAt.After(untilTime)) {
+				filtered = append(filtered, res)
 			}
 		}
 		results = filtered
 	}
-	if len(results) == 0 {
-		fmt.Println("No results found")
+	if starredOnly {
+		var filtered []db.Entry
+		for _, res := range results {
+			if res.Starred {
+				filtered = append(filtered, res)
+			}
+		}
+		results = filtered
 	}
 	if ascending && descending {
 		return fmt.Errorf("cannot sort by both ascending and descending")
@@ -134,17 +242,88 @@ func refineSearch() error {
 			return results[i].CreatedAt.After(results[j].CreatedAt)
 		})
 	}
-	if first {
-		utils.OpenInNvim(results[0].FilePath, config.CONFIG.START_POS, false)
+	if first && len(results) > 0 {
+		if findJSON {
+			results = results[:1]
+		} else {
+			openEntry(&results[0], config.CONFIG.START_POS, false, false)
+		}
 	}
 
 	searchResults = results
 	return nil
 }
 
-func promptLoop() error {
+// Set the search tags from arguments, applying any @name saved searches,
+// and parse the options that need it
+func prepareSearch(args []string) error {
+	if err := expandSearchArgs(args); err != nil {
+		return fmt.Errorf("Error loading saved search: %v", err)
+	}
+	conditions, err := parseWhere(searchWhere)
+	if err != nil {
+		return fmt.Errorf("Error in --where: %v", err)
+	}
+	whereConditions = conditions
+	return parseDateRange(time.Now())
+}
+
+// Turn --since and --until into the times refineSearch filters on
+func parseDateRange(now time.Time) error {
+	sinceTime, untilTime = time.Time{}, time.Time{}
+	var err error
+	if searchSince != "" {
+		if sinceTime, err = parseDateExpr(searchSince, now, false); err != nil {
+			return fmt.Errorf("Error in --since: %v", err)
+		}
+	}
+	if searchUntil != "" {
+		if untilTime, err = parseDateExpr(searchUntil, now, true); err != nil {
+			return fmt.Errorf("Error in --until: %v", err)
+		}
+	}
+	return nil
+}
+
+// Get the info message for the current results.
+// When nothing matched, suggests existing tags close to unknown search tags.
+func searchMessage() string {
+	if len(searchResults) > 0 {
+		return ""
+	}
+
+	var hints []string
+	for _, tag := range searchTags {
+		if db.USERDB.TagExists(tag) {
+			continue
+		}
+		suggestions, err := db.USERDB.SuggestTags(tag, 3)
+		if err != nil || len(suggestions) == 0 {
+			continue
+		}
+		hints = append(hints, fmt.Sprintf("'%s' -> %s", tag, strings.Join(suggestions, ", ")))
+	}
+
+	msg := "No results found"
+	if len(hints) > 0 {
+		msg += ". Did you mean: " + strings.Join(hints, "; ")
+	}
+	return msg
+}
+
+func promptLoop(startMsg string) error {
 	currentMode := ui.SearchDisplay
-	currentMsg := ""
+	currentMsg := searchMessage()
+	if startMsg != "" {
+		currentMsg = startMsg
+	}
+
+	reader, err := newPromptReader()
+	if err != nil {
+		return fmt.Errorf("failed to start prompt: %v", err)
+	}
+	defer reader.Close()
+
 	currentList := searchResults
 	for {
 		if currentMode == ui.SearchDisplay {
@@ -154,12 +333,13 @@ func promptLoop() error {
 		}
 
 		ui.Render(currentMode, currentList, searchTags, currentMsg)
-		fmt.Print("Your decision: ")
 
-		var input string
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			input = scanner.Text()
+		input, err := reader.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		} else if err != nil {
+			// Ctrl-D or the end of piped input
+			return nil
 		}
 
 		inputArr := strings.Split(strings.ToLower(strings.Trim(input, " ")), " ")
@@ -177,6 +357,8 @@ func promptLoop() error {
 						ascending = false
 						descending = false
 						originalsOnly = false
+						starredOnly = false
+						recursive = false
 
 						var tempTags []string
 						for _, arg := range newArgs {
@@ -193,6 +375,10 @@ func promptLoop() error {
 									descending = true
 								case "o":
 									originalsOnly = true
+								case "r":
+									recursive = true
+								case "s":
+									starredOnly = true
 								default:
 									currentMode = ui.SearchDisplay
 									currentMsg = "Not a recognized flag"
@@ -202,8 +388,13 @@ func promptLoop() error {
 								tempTags = append(tempTags, arg)
 							}
 						}
-						searchTags = tempTags
+						if err := prepareSearch(tempTags); err != nil {
+							currentMode = ui.SearchDisplay
+							currentMsg = err.Error()
+							break
+						}
 						refineSearch()
+						currentMsg = searchMessage()
 					} else {
 						currentMode = ui.SearchDisplay
 						currentMsg = "You must supply at least one tag to search for."
@@ -221,6 +412,11 @@ func promptLoop() error {
 					ascending = false
 					descending = false
 					originalsOnly = false
+					recursive = false
+					starredOnly = false
+					whereExpr = ""
+					sinceExpr = ""
+					untilExpr = ""
 
 					var tempTags []string
 					for _, arg := range newArgs {
@@ -237,6 +433,10 @@ func promptLoop() error {
 								descending = true
 							case "o":
 								originalsOnly = true
+							case "r":
+								recursive = true
+							case "s":
+								starredOnly = true
 							default:
 								currentMode = ui.SearchDisplay
 								currentMsg = "Not a recognized flag"
@@ -246,52 +446,97 @@ func promptLoop() error {
 							tempTags = append(tempTags, arg)
 						}
 					}
-					searchTags = tempTags
+					if err := prepareSearch(tempTags); err != nil {
+						currentMode = ui.SearchDisplay
+						currentMsg = err.Error()
+						break
+					}
 					newSearch()
+					currentMsg = searchMessage()
 				} else {
 					currentMode = ui.SearchDisplay
 					currentMsg = "You must supply at least one tag to search for."
 					break
 				}
-			case "a":
-
-				if len(searchResults) > 0 {
-					var tempList []string
-					for _, arg := range newArgs {
-						selectedNumber,
-							err := strconv.Atoi(arg)
-						if err != nil || selectedNumber < 1 || selectedNumber > len(searchResults) {
-							fmt.Println("Invalid selection:"+arg, err)
-						}
-						mergeList = append(mergeList, searchResults[selectedNumber-1])
-						tempList = append(tempList, strconv.Itoa(selectedNumber))
-					}
-					currentMode = ui.SearchDisplay
-					currentMsg = strings.Join(tempList, ", ") + " added to volume list"
-					break
-				} else {
-					currentMode = ui.SearchDisplay
-					currentMsg = "ADD WHAT?!"
+			case "a", "w":
+				currentMode = ui.SearchDisplay
+				selection := newArgs
+				if newCmd == "w" {
+					selection = []string{"*"}
+				}
+				selected, err := selectEntries(searchResults, selection)
+				if err != nil {
+					currentMsg = err.Error()
 					break
 				}
+				added := addToMergeList(selected)
+				currentMsg = fmt.Sprintf("%d added to volume list", added)
+				break
 			case "d":
-				for _, arg := range newArgs {
-					selectedNumber,
-						err := strconv.Atoi(arg)
-					if err != nil || selectedNumber < 1 || selectedNumber > len(searchResults) {
-						fmt.Println("Invalid selection:"+arg, err)
+				currentMode = ui.SearchDisplay
+				selected, err := selectEntries(searchResults, newArgs)
+				if err != nil {
+					currentMsg = err.Error()
+					break
+				}
+				if len(selected) > 1 {
+					answer := strings.ToLower(promptAsk(reader)(fmt.Sprintf("Delete %d entries permanently? [y/N] ", len(selected))))
+					if answer != "y" && answer != "yes" {
+						currentMsg = "Delete cancelled"
 						break
 					}
-					delErr := db.USERDB.DeleteEntry(searchResults[selectedNumber-1].ID)
+				}
+				deleted := make(map[uint]bool)
+				var messages []string
+				for i := range selected {
+					warning, delErr := deleteEntry(&selected[i])
 					if delErr != nil {
-						return fmt.Errorf("Error deleting entry: %v", delErr)
+						messages = append(messages, delErr.Error())
+						break
 					}
-					searchResults = append(
-						searchResults[:selectedNumber-1], searchResults[selectedNumber:]...)
-					currentMode = ui.SearchDisplay
-					currentMsg = "Entry deleted"
+					deleted[selected[i].ID] = true
+					if warning != "" {
+						messages = append(messages, warning)
+					}
+				}
+				searchResults = withoutEntries(searchResults, deleted)
+				mergeList = withoutEntries(mergeList, deleted)
+				currentMsg = strings.Join(append([]string{fmt.Sprintf("%d deleted", len(deleted))}, messages...), ". ")
+				break
+			case "star", "unstar", "pin", "unpin":
+				currentMode = ui.SearchDisplay
+				indexes, err := parseSelection(newArgs, len(searchResults))
+				if err != nil {
+					currentMsg = err.Error()
+					break
+				}
+				marked := 0
+				for _, i := range indexes {
+					if markErr := markEntry(&searchResults[i], newCmd); markErr != nil {
+						currentMsg = markErr.Error()
+						break
+					}
+					marked++
+				}
+				if marked > 0 {
+					currentMsg = fmt.Sprintf("%d %s", marked, markedMessages[newCmd])
+				}
+				break
+			case "t":
+				currentMode = ui.SearchDisplay
+				currentMsg = retagSelection(searchResults, newArgs)
+				break
+			case "s":
+				currentMode = ui.SearchDisplay
+				if len(newArgs) != 1 {
+					currentMsg = "Give the search a name: s [name]"
+					break
+				}
+				if err := db.USERDB.SaveSearch(currentSearch(newArgs[0])); err != nil {
+					currentMsg = err.Error()
 					break
 				}
+				currentMsg = "Saved search @" + strings.TrimPrefix(newArgs[0], "@")
 				break
 			case "v":
 				if len(mergeList) > 0 {
@@ -307,28 +552,36 @@ func promptLoop() error {
 			case "q":
 				os.Exit(0)
 			default:
-				selectedNumber,
-					err := strconv.Atoi(input)
-				if err != nil || selectedNumber < 1 || selectedNumber > len(searchResults) {
-					currentMode = ui.SearchDisplay
-					currentMsg = "Invalid selection. Please enter a valid option."
-					break
-				}
-				utils.OpenInNvim(searchResults[selectedNumber-1].FilePath, 0, false)
+				currentMode = ui.SearchDisplay
+				currentMsg = openSelection(searchResults, inputArr)
 			}
 		} else {
 			switch strings.ToLower(newCmd) {
 			case "m":
-				if len(mergeList) > 1 && newArgs[0] != "" {
+				groupBy, nameArgs, err := parseGroupBy(newArgs)
+				if err != nil {
+					currentMode = ui.MergeDisplay
+					currentMsg = err.Error()
+					break
+				}
+				if len(mergeList) > 1 && len(nameArgs) == 0 {
+					currentMode = ui.MergeDisplay
+					currentMsg = "Give the volume a name: m [--group-by tag|day|month] [name]..."
+					break
+				}
+				if len(mergeList) > 1 {
+					volumeGroupBy = groupBy
+					// Keep the case of the name as typed
+					_, nameArgs, _ = parseGroupBy(strings.Fields(input)[1:])
 					newVolume,
-						err := createVolume(newArgs)
+						err := createVolume(nameArgs, promptAsk(reader))
 					if err != nil {
-						currentMode = ui.SearchDisplay
+						currentMode = ui.MergeDisplay
 						currentMsg = fmt.Sprintf("Error merging entries: %v", err)
 						break
 					} else {
 						fmt.Println("Volume Created Successfully")
-						utils.OpenInNvim(newVolume, config.CONFIG.START_POS, false)
+						openEntry(newVolume, config.CONFIG.START_POS, false, false)
 						os.Exit(0)
 					}
 				} else {
@@ -336,103 +589,338 @@ func promptLoop() error {
 					currentMsg = "Add at least two entries to your volume list first..."
 					break
 				}
+			case "mv":
+				currentMode = ui.MergeDisplay
+				currentMsg = moveInMergeList(newArgs)
+				break
+			case "sort":
+				currentMode = ui.MergeDisplay
+				currentMsg = sortMergeList(newArgs)
+				break
+			case "p":
+				currentMode = ui.MergeDisplay
+				groupBy, _, err := parseGroupBy(newArgs)
+				if err != nil {
+					currentMsg = err.Error()
+					break
+				}
+				volumeGroupBy = groupBy
+				currentMsg = volumeOutline(mergeList)
+				break
 			case "b":
 				ui.Render(ui.SearchDisplay, searchResults, searchTags, "")
 				currentMode = ui.SearchDisplay
 				currentMsg = ""
 				break
 			case "d":
-				if len(newArgs) > 0 && len(mergeList) > 0 {
-					for _, arg := range newArgs {
-						selectedNumber,
-							err := strconv.Atoi(arg)
-						if err != nil || selectedNumber < 1 || selectedNumber > len(mergeList) {
-							currentMode = ui.MergeDisplay
-							currentMsg = "Invalid selection. Please enter a valid option."
-							break
-						}
-
-						mergeList = append(
-							mergeList[:selectedNumber-1],
-							mergeList[selectedNumber:]...)
-					}
-					currentMode = ui.MergeDisplay
-					currentMsg = "Merge list updated"
+				currentMode = ui.MergeDisplay
+				selected, err := selectEntries(mergeList, newArgs)
+				if err != nil {
+					currentMsg = err.Error()
 					break
-
 				}
+				removed := make(map[uint]bool)
+				for _, entry := range selected {
+					removed[entry.ID] = true
+				}
+				mergeList = withoutEntries(mergeList, removed)
+				currentMsg = "Merge list updated"
+				if len(mergeList) == 0 {
+					currentMode = ui.SearchDisplay
+				}
+				break
+			case "t":
 				currentMode = ui.MergeDisplay
-				currentMsg = "DELETE WHAT?!"
+				currentMsg = retagSelection(mergeList, newArgs)
 				break
-
 			case "q":
 				os.Exit(0)
 			default:
-				currentMode = ui.SearchDisplay
-				currentMsg = "Invalid command"
+				currentMode = ui.MergeDisplay
+				currentMsg = openSelection(mergeList, inputArr)
+			}
+		}
+	}
+}
+
+// Add entries to the merge list, skipping ones already in it.
+// Returns how many were added.
+func addToMergeList(entries []db.Entry) int {
+	added := 0
+	for _, entry := range entries {
+		inList := false
+		for _, existing := range mergeList {
+			if existing.ID == entry.ID {
+				inList = true
 				break
 			}
 		}
+		if !inList {
+			mergeList = append(mergeList, entry)
+			added++
+		}
 	}
+	return added
 }
 
-func createVolume(newArgs []string) (string, error) {
-	allTags := getVolTags()
-	allOriginals := getVolOg()
+// Move a merge list item to another position, e.g. mv 3 1
+func moveInMergeList(args []string) string {
+	if len(args) != 2 {
+		return "Give the item to move and where to: mv [from] [to]"
+	}
+	from, fromErr := strconv.Atoi(args[0])
+	to, toErr := strconv.Atoi(args[1])
+	if fromErr != nil || toErr != nil || from < 1 || to < 1 || from > len(mergeList) || to > len(mergeList) {
+		return fmt.Sprintf("Invalid position, pick from 1-%d", len(mergeList))
+	}
+
+	entry := mergeList[from-1]
+	mergeList = append(mergeList[:from-1], mergeList[from:]...)
+	mergeList = append(mergeList[:to-1], append([]db.Entry{entry}, mergeList[to-1:]...)...)
+	return fmt.Sprintf("Moved %d to %d", from, to)
+}
+
+// Sort the merge list by date, tag or title, optionally descending
+func sortMergeList(args []string) string {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "desc" && args[1] != "asc") {
+		return "Sort by what? sort date|tag|title [asc|desc]"
+	}
+
+	var less func(a, b db.Entry) bool
+	switch args[0] {
+	case "date":
+		less = func(a, b db.Entry) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "tag":
+		shared := sharedTags(mergeList)
+		less = func(a, b db.Entry) bool {
+			tagA, tagB := primaryTag(a, searchTags, shared), primaryTag(b, searchTags, shared)
+			if tagA != tagB {
+				return tagA < tagB
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case "title":
+		less = func(a, b db.Entry) bool {
+			return strings.ToLower(a.DisplayTitle()) < strings.ToLower(b.DisplayTitle())
+		}
+	default:
+		return "Sort by what? sort date|tag|title [asc|desc]"
+	}
 
-	day := time.Now().Format("Monday")
-	date := time.Now().Format("01/02/2006")
-	time := time.Now().Format("15:04")
+	descending := len(args) == 2 && args[1] == "desc"
+	sort.SliceStable(mergeList, func(i, j int) bool {
+		if descending {
+			return less(mergeList[j], mergeList[i])
+		}
+		return less(mergeList[i], mergeList[j])
+	})
+	return "Sorted by " + strings.Join(args, " ")
+}
 
-	maxWidth := 80
+// Outline of the volume the merge list would become
+func volumeOutline(entries []db.Entry) string {
+	if len(entries) == 0 {
+		return "The merge list is empty"
+	}
+	lines := []string{"Volume outline:"}
+	for _, group := range groupVolume(entries, volumeGroupBy, searchTags) {
+		indent := "  "
+		if group.Title != "" {
+			lines = append(lines, indent+group.Title)
+			indent += "  "
+		}
+		for _, entry := range group.Entries {
+			lines = append(lines, fmt.Sprintf("%s- %s (%s)", indent, entry.DisplayTitle(), entry.CreatedAt.Format("01/02/2006")))
+		}
+		for _, entry := range group.SeeAlso {
+			lines = append(lines, fmt.Sprintf("%s  see also %s", indent, entry.DisplayTitle()))
+		}
+	}
+	return strings.Join(lines, "\n ")
+}
 
-	name := strings.Join(newArgs, " ")
-	filepath := config.CONFIG.VOLUME_DIR + name + ".md"
+// Take a -g/--group-by option out of merge prompt arguments
+func parseGroupBy(args []string) (string, []string, error) {
+	groupBy := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-g" || arg == "--group-by":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("%s needs one of %s", arg, strings.Join(volumeGroupings, ", "))
+			}
+			i++
+			groupBy = args[i]
+		case strings.HasPrefix(arg, "--group-by="):
+			groupBy = strings.TrimPrefix(arg, "--group-by=")
+		case arg != "":
+			rest = append(rest, arg)
+		}
+	}
+	if groupBy != "" && !utils.SliceStrContains(volumeGroupings, groupBy) {
+		return "", nil, fmt.Errorf("unknown grouping %q, use %s", groupBy, strings.Join(volumeGroupings, ", "))
+	}
+	return groupBy, rest, nil
+}
 
-	_,
-		err := db.USERDB.InsertEntry(
-		name,
-		allTags,
-		allOriginals,
-		filepath,
-	)
+// Open the selected entries one after the other
+func openSelection(entries []db.Entry, args []string) string {
+	if _, rest := splitSelection(args); len(rest) > 0 {
+		return "Invalid command"
+	}
+	selected, err := selectEntries(entries, args)
 	if err != nil {
-		return "",
-			fmt.Errorf("Error adding new entry: %v", err)
+		return err.Error()
+	}
+	for i := range selected {
+		if err := openEntry(&selected[i], 0, false, false); err != nil {
+			return err.Error()
+		}
 	}
+	return ""
+}
 
-	lines := []string{
-		"",
-		fmt.Sprintf("%*s", maxWidth, day),
-		fmt.Sprintf("%*s", maxWidth, date),
-		fmt.Sprintf("%*s", maxWidth, time),
-		fmt.Sprintf("# %s", name),
-		"",
-		"---",
-		"",
-		"",
+// Retag the selected entries: tag or +tag adds a tag and -tag removes one.
+// Only the first argument is the selection so tags can be numbers, like 2024.
+// Both lists are updated as they can hold the same entries.
+func retagSelection(entries []db.Entry, args []string) string {
+	if len(args) == 0 {
+		return "Give tags to add or -tags to remove: t [selection] tag -tag"
+	}
+	selection, changes := args[:1], args[1:]
+	var add, remove []string
+	for _, change := range changes {
+		if strings.HasPrefix(change, "-") {
+			remove = append(remove, change[1:])
+		} else {
+			add = append(add, strings.TrimPrefix(change, "+"))
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return "Give tags to add or -tags to remove: t [selection] tag -tag"
+	}
+	selected, err := selectEntries(entries, selection)
+	if err != nil {
+		return err.Error()
 	}
-	// TODO: warn for long running process if they add a crazy number of entries perhaps limit the number of entries later
 
-	for _, file := range mergeList {
-		tempLines,
-			err := utils.GetLines(file.FilePath)
+	for i := range selected {
+		if err := db.USERDB.AddTags(&selected[i], add); err != nil {
+			return err.Error()
+		}
+		if err := db.USERDB.RemoveTags(&selected[i], remove); err != nil {
+			return err.Error()
+		}
+		updated, err := db.USERDB.GetEntry(selected[i].ID)
 		if err != nil {
-			return "",
-				fmt.Errorf("Error reading original entries: %v", err)
+			return err.Error()
+		}
+		for _, list := range [][]db.Entry{searchResults, mergeList} {
+			for j := range list {
+				if list[j].ID == updated.ID {
+					list[j].Tags = updated.Tags
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%d retagged", len(selected))
+}
+
+var markedMessages = map[string]string{
+	"star":   "starred",
+	"unstar": "unstarred",
+	"pin":    "pinned",
+	"unpin":  "unpinned",
+}
+
+// Apply a star, unstar, pin or unpin action to an entry
+func markEntry(entry *db.Entry, action string) error {
+	var err error
+	switch action {
+	case "star", "unstar":
+		entry.Starred = action == "star"
+		err = db.USERDB.SetStarred(entry.ID, entry.Starred)
+	case "pin", "unpin":
+		entry.Pinned = action == "pin"
+		err = db.USERDB.SetPinned(entry.ID, entry.Pinned)
+	default:
+		err = fmt.Errorf("unknown action %s", action)
+	}
+	return err
+}
+
+// Merge the merge list into a new volume. ask decides what happens
+// when the name is already taken.
+func createVolume(newArgs []string, ask askFunc) (*db.Entry, error) {
+	name, filepath, existing, action, err := chooseVolumeName(strings.Join(newArgs, " "), ask)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// A volume can't be merged into itself
+		mergeList = withoutEntries(mergeList, map[uint]bool{existing.ID: true})
+	}
+	allTags := getVolTags()
+	allOriginals := getVolOg()
+	if existing != nil {
+		allOriginals = withoutEntries(allOriginals, map[uint]bool{existing.ID: true})
+	}
+	if action == "append" {
+		if err := updateVolume(existing, mergeList, volumeGroupBy); err != nil {
+			return nil, fmt.Errorf("Error adding to volume: %v", err)
 		}
-		lines = append(lines, tempLines[config.CONFIG.START_POS-1:]...)
-		lines = append(lines, "")
-		lines = append(lines, "---")
-		lines = append(lines, "")
+		return existing, nil
+	}
+	if existing != nil {
+		name = existing.Name
 	}
+
+	lines := volumeHeader(name, time.Now())
+	// TODO: warn for long running process if they add a crazy number of entries perhaps limit the number of entries later
+
+	body, err := volumeBody(filepath, mergeList, volumeGroupBy, searchTags, nil)
+	if err != nil {
+		return nil, err
+	}
+	lines = append(lines, body...)
 	writeErr := utils.WriteLines(filepath, lines)
 	if writeErr != nil {
-		return "",
+		return nil,
 			fmt.Errorf("Error writing new file: %v", writeErr)
 	}
 
-	return filepath,
+	volume := existing
+	if volume != nil {
+		err = db.USERDB.ReplaceVolume(volume, allTags, allOriginals)
+	} else {
+		volume, err = db.USERDB.InsertEntry(
+			name,
+			allTags,
+			allOriginals,
+			filepath,
+		)
+	}
+	if err != nil {
+		return nil,
+			fmt.Errorf("Error adding new entry: %v", err)
+	}
+
+	if err := db.USERDB.SetVolumeGroupBy(volume, volumeGroupBy); err != nil {
+		return nil,
+			fmt.Errorf("Error saving volume grouping: %v", err)
+	}
+
+	if attachErr := carryAttachments(volume, mergeList); attachErr != nil {
+		return nil,
+			fmt.Errorf("Error copying attachments: %v", attachErr)
+	}
+
+	if hookErr := hooks.Run(hooks.PostMerge, volume); hookErr != nil {
+		fmt.Println("Warning:", hookErr)
+	}
+
+	return volume,
 		nil
 }
 
@@ -463,3 +951,131 @@ func getVolOg() []db.Entry {
 	}
 	return temp
 }
+
+// Ways a volume can be split into sections
+var volumeGroupings = []string{"day", "month", "tag"}
+
+type volumeGroup struct {
+	Title   string
+	Entries []db.Entry
+	// Entries filed under another tag that also have this one
+	SeeAlso []db.Entry
+}
+
+// Split entries into volume sections, keeping their order within each section.
+// "day" and "month" bucket by creation date, "tag" by each entry's primary tag
+// with cross references under its other tags, anything else yields a single
+// untitled section.
+func groupVolume(entries []db.Entry, groupBy string, preferredTags []string) []volumeGroup {
+	switch groupBy {
+	case "day", "month":
+		layout, keyLayout := "Monday 01/02/2006", "2006-01-02"
+		if groupBy == "month" {
+			layout, keyLayout = "January 2006", "2006-01"
+		}
+		// Entries keep their order within a date, dates go oldest first
+		byDate := make(map[string]*volumeGroup)
+		var keys []string
+		for _, entry := range entries {
+			key := entry.CreatedAt.Format(keyLayout)
+			if _, ok := byDate[key]; !ok {
+				byDate[key] = &volumeGroup{Title: entry.CreatedAt.Format(layout)}
+				keys = append(keys, key)
+			}
+			byDate[key].Entries = append(byDate[key].Entries, entry)
+		}
+		sort.Strings(keys)
+
+		var groups []volumeGroup
+		for _, key := range keys {
+			groups = append(groups, *byDate[key])
+		}
+		return groups
+	case "tag":
+		shared := sharedTags(entries)
+		byTag := make(map[string][]db.Entry)
+		var tags []string
+		for _, entry := range entries {
+			tag := primaryTag(entry, preferredTags, shared)
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], entry)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if tags[i] == "" || tags[j] == "" {
+				return tags[j] == ""
+			}
+			return tags[i] < tags[j]
+		})
+
+		var groups []volumeGroup
+		for _, tag := range tags {
+			title := tag
+			if title == "" {
+				title = "untagged"
+			}
+			group := volumeGroup{Title: title, Entries: byTag[tag]}
+			if tag != "" {
+				for _, entry := range entries {
+					if primaryTag(entry, preferredTags, shared) != tag && hasAnyTag(entry, []string{tag}) {
+						group.SeeAlso = append(group.SeeAlso, entry)
+					}
+				}
+			}
+			groups = append(groups, group)
+		}
+		return groups
+	default:
+		return []volumeGroup{{Entries: entries}}
+	}
+}
+
+// The tag an entry is filed under when grouping by tag.
+// Prefers one of the given tags, otherwise the alphabetically first tag.
+// Tags shared by every entry being grouped only count when the entry has no other.
+func primaryTag(entry db.Entry, preferredTags []string, sharedTags []string) string {
+	var names, shared []string
+	for _, tag := range entry.Tags {
+		if utils.SliceStrContains(sharedTags, tag.TagName) {
+			shared = append(shared, tag.TagName)
+		} else {
+			names = append(names, tag.TagName)
+		}
+	}
+	if len(names) == 0 {
+		names = shared
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if utils.SliceStrContains(preferredTags, name) {
+			return name
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// Get the tags every one of several entries has
+func sharedTags(entries []db.Entry) []string {
+	if len(entries) < 2 {
+		return nil
+	}
+	var shared []string
+	for _, tag := range entries[0].Tags {
+		inAll := true
+		for _, entry := range entries[1:] {
+			if !hasAnyTag(entry, []string{tag.TagName}) {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			shared = append(shared, tag.TagName)
+		}
+	}
+	return shared
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+)
+
+// Commands of the find prompt in each mode, for completion
+var (
+	searchPromptCommands = []string{"r", "n", "a", "w", "d", "t", "star", "unstar", "pin", "unpin", "s", "v", "q"}
+	mergePromptCommands  = []string{"m", "d", "t", "mv", "sort", "p", "b", "q"}
+	searchPromptFlags    = []string{"-i", "-f", "-a", "-d", "-o", "-r", "-s"}
+)
+
+// Line editor for the find prompt with history kept across sessions.
+// Ctrl-R searches the history and Tab completes commands, flags, tags and saved searches.
+func newPromptReader() (*readline.Instance, error) {
+	return readline.NewEx(&readline.Config{
+		Prompt:            "Your decision: ",
+		HistoryFile:       filepath.Join(config.ConfigDir, "find_history"),
+		HistorySearchFold: true,
+		AutoComplete:      promptCompleter{},
+	})
+}
+
+// Ask a question on the prompt's line, leaving it out of the history
+func promptAsk(reader *readline.Instance) askFunc {
+	return func(question string) string {
+		reader.SetPrompt(question)
+		reader.HistoryDisable()
+		defer reader.SetPrompt("Your decision: ")
+		defer reader.HistoryEnable()
+		answer, _ := reader.Readline()
+		return strings.TrimSpace(answer)
+	}
+}
+
+type promptCompleter struct{}
+
+// Complete the word before the cursor based on the command it belongs to
+func (promptCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	before := string(line[:pos])
+	words := strings.Fields(before)
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(before, " ") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	var candidates []string
+	switch {
+	case len(words) == 0 && ui.CurrentDisplay == ui.MergeDisplay:
+		candidates = mergePromptCommands
+	case len(words) == 0:
+		candidates = searchPromptCommands
+	case words[0] == "t":
+		// Tags to add or remove, after the selection
+		sign := ""
+		if strings.HasPrefix(current, "-") || strings.HasPrefix(current, "+") {
+			sign = current[:1]
+		}
+		names, _ := db.USERDB.TagNames(current[len(sign):])
+		for _, name := range names {
+			candidates = append(candidates, sign+name)
+		}
+	case (words[0] == "m" || words[0] == "p") && (words[len(words)-1] == "-g" || words[len(words)-1] == "--group-by"):
+		candidates = volumeGroupings
+	case (words[0] == "m" || words[0] == "p") && strings.HasPrefix(current, "-"):
+		candidates = []string{"--group-by"}
+	case words[0] == "sort" && len(words) == 1:
+		candidates = []string{"date", "tag", "title"}
+	case words[0] == "sort" && len(words) == 2:
+		candidates = []string{"asc", "desc"}
+	case ui.CurrentDisplay == ui.MergeDisplay:
+		return nil, 0
+	case words[0] == "s":
+		candidates = savedSearchNames("")
+	case words[0] != "r" && words[0] != "n":
+		return nil, 0
+	case strings.HasPrefix(current, "-"):
+		candidates = searchPromptFlags
+	case strings.HasPrefix(current, "@") && words[0] == "n":
+		candidates = savedSearchNames("@")
+	default:
+		candidates, _ = db.USERDB.TagNames(current)
+	}
+
+	var completions [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			completions = append(completions, []rune(candidate[len(current):]+" "))
+		}
+	}
+	return completions, len([]rune(current))
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeName    string
+	mergeGroupBy string
+)
+
+var mergeCmd = &cobra.Command{
+	Use:               "merge [entries]... --name [name]",
+	Short:             "Merge entries by id, name or tags into a volume",
+	Args:              batchArgs,
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if mergeGroupBy != "" && !utils.SliceStrContains(volumeGroupings, mergeGroupBy) {
+			return fmt.Errorf("unknown grouping %q, use %s", mergeGroupBy, strings.Join(volumeGroupings, ", "))
+		}
+		entries, err := batchEntries(args)
+		if err != nil {
+			return err
+		}
+		if len(entries) < 2 {
+			return fmt.Errorf("Error merging entries: found %d, a volume needs at least two", len(entries))
+		}
+		fileName, err := volumeFileName(strings.TrimSpace(mergeName))
+		if err != nil {
+			return fmt.Errorf("Error merging entries: %v", err)
+		}
+
+		mergeList = entries
+		volumeGroupBy = mergeGroupBy
+		if dryRun {
+			fmt.Printf("Would merge %d entries into %s\n %s\n", len(entries), config.CONFIG.VOLUME_DIR+fileName, volumeOutline(mergeList))
+			if existing, err := db.USERDB.FindVolume(mergeName, config.CONFIG.VOLUME_DIR+fileName); err == nil && existing != nil {
+				fmt.Printf("Volume '%s' already exists\n", existing.Name)
+			}
+			return nil
+		}
+		fmt.Printf("Merging %d entries:\n%s\n", len(entries), batchList(entries))
+		if !confirmBatch(fmt.Sprintf("Merge them into '%s'?", mergeName)) {
+			return fmt.Errorf("Merge cancelled")
+		}
+
+		// With --yes a taken name is never overwritten
+		ask := askStdin
+		if assumeYes {
+			ask = func(question string) string { return "c" }
+		}
+		volume, err := createVolume(strings.Fields(mergeName), ask)
+		if err != nil {
+			return fmt.Errorf("Error merging entries: %v", err)
+		}
+		fmt.Println("Saved volume:", volume.FilePath)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	// Flags
+	addBatchFlags(mergeCmd)
+	mergeCmd.Flags().StringVarP(&mergeName, "name", "n", "", "Name of the volume")
+	mergeCmd.Flags().StringVarP(&mergeGroupBy, "group-by", "g", "", "Group the volume into sections by day, month or tag")
+	mergeCmd.MarkFlagRequired("name")
+
+	// Completion
+	mergeCmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions(volumeGroupings, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/projectz-ro/journalz-ro/config"
@@ -10,10 +14,25 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	noSuggest      bool
+	properties     []string
+	promptCategory string
+)
+
 var newCmd = &cobra.Command{
-	Use:   "new [tags]",
-	Short: "Create a new entry",
+	Use:               "new [tags]",
+	Short:             "Create a new entry",
+	ValidArgsFunction: completeTags,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, pair := range properties {
+			if !strings.Contains(pair, "=") {
+				return fmt.Errorf("Error creating entry: --set %s is not key=value", pair)
+			}
+		}
+		if !noSuggest {
+			args = suggestTags(args)
+		}
 		err := createEntry(args)
 		if err != nil {
 			return fmt.Errorf("Error creating entry: %v", err)
@@ -24,6 +43,52 @@ var newCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(newCmd)
+
+	// Flags
+	newCmd.Flags().BoolVarP(&noSuggest, "no-suggest", "n", false, "Don't suggest existing tags close to new ones")
+	newCmd.Flags().StringArrayVarP(&properties, "set", "s", nil, "Set a property on the entry, e.g. --set mood=4")
+	newCmd.Flags().StringVarP(&promptCategory, "prompt", "p", "", "Start the entry with a journaling prompt, optionally from a category (--prompt=work)")
+	newCmd.Flags().Lookup("prompt").NoOptDefVal = anyPromptCategory
+
+	// Completion
+	newCmd.RegisterFlagCompletionFunc("prompt", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return promptCategories(loadPrompts()), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// Warn about brand-new tags that are close to existing ones
+// and let the user swap them for a suggestion
+func suggestTags(tags []string) []string {
+	reader := bufio.NewReader(os.Stdin)
+	var result []string
+	for _, tag := range db.USERDB.ResolveAliases(tags) {
+		if db.USERDB.TagExists(tag) {
+			result = append(result, tag)
+			continue
+		}
+		suggestions, err := db.USERDB.SuggestTags(tag, 3)
+		if err != nil || len(suggestions) == 0 {
+			result = append(result, tag)
+			continue
+		}
+
+		fmt.Printf("'%s' is a new tag but looks like an existing one:\n", tag)
+		for i, suggestion := range suggestions {
+			fmt.Printf("\t%d) %s\n", i+1, suggestion)
+		}
+		fmt.Printf("Use which? [1-%d, Enter keeps '%s']: ", len(suggestions), tag)
+
+		input, _ := reader.ReadString('\n')
+		choice, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || choice < 1 || choice > len(suggestions) {
+			result = append(result, tag)
+			continue
+		}
+		if !utils.SliceStrContains(result, suggestions[choice-1]) {
+			result = append(result, suggestions[choice-1])
+		}
+	}
+	return result
 }
 
 func createEntry(args []string) error {
@@ -34,20 +99,20 @@ func createEntry(args []string) error {
 	time := time.Now().Format("15:04")
 
 	maxWidth := 80
+	startPos := config.CONFIG.START_POS
+
+	prompt, category := "", ""
+	if promptCategory != "" {
+		var err error
+		prompt, category, err = pickPrompt(promptCategory, args)
+		if err != nil {
+			return fmt.Errorf("Error choosing prompt: %v", err)
+		}
+	}
 
 	title := fmt.Sprintf("Entry_%s.md", currentDate)
 	filepath := config.CONFIG.ENTRY_DIR + title
 
-	_, err := db.USERDB.InsertEntry(
-		title,
-		args,
-		nil,
-		filepath,
-	)
-	if err != nil {
-		return fmt.Errorf("Error adding new entry: %v", err)
-	}
-
 	lines := []string{
 		"",
 		fmt.Sprintf("%*s", maxWidth, day),
@@ -58,14 +123,43 @@ func createEntry(args []string) error {
 		"",
 		"",
 	}
+	if prompt != "" {
+		// Start writing below the prompt
+		lines = append(lines[:len(lines)-1], "> "+prompt, "", "")
+		startPos += 2
+	}
 
 	writeErr := utils.WriteLines(filepath, lines)
 	if writeErr != nil {
 		return fmt.Errorf("Error writing new file: %v", writeErr)
 	}
 
+	entry, err := db.USERDB.InsertEntry(
+		title,
+		args,
+		nil,
+		filepath,
+	)
+	if err != nil {
+		os.Remove(filepath)
+		return fmt.Errorf("Error adding new entry: %v", err)
+	}
+
+	if err := setProperties(entry.ID, properties); err != nil {
+		return fmt.Errorf("Error setting properties: %v", err)
+	}
+
 	fmt.Println("Created new entry:", filepath)
-	utils.OpenInNvim(filepath, config.CONFIG.START_POS, true)
+	err = openEntry(entry, startPos, config.CONFIG.INSERT_ON_NEW, true)
+	if err != nil {
+		return err
+	}
 
+	// Only prompts that were actually written to count as used
+	if prompt != "" {
+		if _, findErr := db.USERDB.FindEntry(strconv.FormatUint(uint64(entry.ID), 10)); findErr == nil {
+			return db.USERDB.RecordPromptUse(prompt, category, entry.ID)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+// Value of --prompt when no category is given
+const anyPromptCategory = "any"
+
+// Prompts that ship with the tool. Files in the config prompts dir add to these.
+var builtinPrompts = map[string][]string{
+	"reflection": {
+		"What took up most of your attention today, and was it worth it?",
+		"What is something you changed your mind about recently?",
+		"What would you tell yourself from a year ago?",
+		"What are you avoiding right now, and why?",
+		"Describe a moment today you'd like to remember.",
+		"What drained your energy today, and what restored it?",
+	},
+	"gratitude": {
+		"Name three small things that went right today.",
+		"Who made your day a little easier, and how?",
+		"What is something you use every day that you're glad to have?",
+		"What is a recent problem that turned out better than expected?",
+	},
+	"work": {
+		"What did you finish today, and what is still open?",
+		"What is blocking you, and who could help unblock it?",
+		"What did you learn at work this week?",
+		"If you could only get one thing done tomorrow, what would it be?",
+		"What decision are you putting off?",
+	},
+	"health": {
+		"How did you sleep, and what might have affected it?",
+		"How did your body feel today?",
+		"What did you eat today that made you feel good?",
+		"What is one habit you'd like to build this month?",
+	},
+	"creativity": {
+		"Describe an idea you keep coming back to.",
+		"What would you make if you knew nobody would ever see it?",
+		"Write about a place you'd like to go back to.",
+		"What is something you noticed today that nobody else did?",
+	},
+}
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts [category]",
+	Short: "List the journaling prompt categories, or the prompts in one",
+	Args:  cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return promptCategories(loadPrompts()), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		library := loadPrompts()
+		if len(args) == 0 {
+			for _, category := range promptCategories(library) {
+				fmt.Printf("%s%s%s (%d)\n", ui.Green, category, ui.Reset, len(library[category]))
+			}
+			return nil
+		}
+
+		prompts, ok := library[args[0]]
+		if !ok {
+			return fmt.Errorf("No prompt category %s, choose from: %s", args[0], strings.Join(promptCategories(library), ", "))
+		}
+		for _, prompt := range prompts {
+			fmt.Println("\t", prompt)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promptsCmd)
+}
+
+// Get every prompt by category, built-in ones first.
+// Each file in the config prompts dir is a category holding one prompt per line.
+// Blank lines and lines starting with # are skipped.
+func loadPrompts() map[string][]string {
+	library := make(map[string][]string)
+	for category, prompts := range builtinPrompts {
+		library[category] = append([]string{}, prompts...)
+	}
+
+	dir := filepath.Join(config.ConfigDir, "prompts")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return library
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		lines, err := utils.GetLines(filepath.Join(dir, file.Name()))
+		if err != nil {
+			fmt.Println("Error reading prompts from "+file.Name(), err)
+			continue
+		}
+		category := strings.ToLower(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") || utils.SliceStrContains(library[category], line) {
+				continue
+			}
+			library[category] = append(library[category], line)
+		}
+	}
+	return library
+}
+
+func promptCategories(library map[string][]string) []string {
+	var categories []string
+	for category := range library {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Choose a prompt that hasn't been used recently.
+// With no category, categories named like one of the tags are preferred over all of them.
+// Returns the prompt and the category it came from.
+func pickPrompt(category string, tags []string) (string, string, error) {
+	library := loadPrompts()
+
+	var categories []string
+	if category != anyPromptCategory {
+		if _, ok := library[category]; !ok {
+			return "", "", fmt.Errorf("no prompt category %s, choose from: %s", category, strings.Join(promptCategories(library), ", "))
+		}
+		categories = []string{category}
+	} else {
+		for _, tag := range db.USERDB.ResolveAliases(tags) {
+			if _, ok := library[tag]; ok {
+				categories = append(categories, tag)
+			}
+		}
+		if len(categories) == 0 {
+			categories = promptCategories(library)
+		}
+	}
+
+	var prompts []string
+	categoryOf := make(map[string]string)
+	for _, c := range categories {
+		for _, prompt := range library[c] {
+			if _, ok := categoryOf[prompt]; !ok {
+				prompts = append(prompts, prompt)
+				categoryOf[prompt] = c
+			}
+		}
+	}
+	if len(prompts) == 0 {
+		return "", "", fmt.Errorf("no prompts available")
+	}
+
+	lastUsed, err := db.USERDB.PromptsLastUsed(prompts)
+	if err != nil {
+		return "", "", err
+	}
+
+	var unused []string
+	for _, prompt := range prompts {
+		if _, ok := lastUsed[prompt]; !ok {
+			unused = append(unused, prompt)
+		}
+	}
+	if len(unused) > 0 {
+		prompt := unused[rand.IntN(len(unused))]
+		return prompt, categoryOf[prompt], nil
+	}
+
+	// Everything has been used, pick from the half used longest ago
+	sort.Slice(prompts, func(i, j int) bool {
+		return lastUsed[prompts[i]] < lastUsed[prompts[j]]
+	})
+	oldest := prompts[:max(1, len(prompts)/2)]
+	prompt := oldest[rand.IntN(len(oldest))]
+	return prompt, categoryOf[prompt], nil
+}
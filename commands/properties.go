@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	andRegex       = regexp.MustCompile(`(?i)\s+and\s+`)
+	conditionRegex = regexp.MustCompile(`^\s*([^\s<>=!]+)\s*(>=|<=|!=|=|>|<)\s*(.*?)\s*$`)
+)
+
+// One comparison of a --where filter, e.g. mood>=4
+type propertyCondition struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set [entry] [key=value]...",
+	Short: "Set properties on an entry, or list them when none are given",
+	Args:  cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeEntries(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := db.USERDB.FindEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("Error finding entry: %v", err)
+		}
+		if len(args) == 1 {
+			return listProperties(entry)
+		}
+		err = setProperties(entry.ID, args[1:])
+		if err != nil {
+			return fmt.Errorf("Error setting properties: %v", err)
+		}
+		return nil
+	},
+}
+
+var unsetCmd = &cobra.Command{
+	Use:   "unset [entry] [key]...",
+	Short: "Remove properties from an entry",
+	Args:  cobra.MinimumNArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeEntries(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := db.USERDB.FindEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("Error finding entry: %v", err)
+		}
+		for _, key := range args[1:] {
+			if err := db.USERDB.UnsetProperty(entry.ID, key); err != nil {
+				return fmt.Errorf("Error unsetting property: %v", err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(unsetCmd)
+}
+
+// Set key=value pairs on an entry
+func setProperties(entryID uint, pairs []string) error {
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%s is not key=value", pair)
+		}
+		if _, err := db.USERDB.SetProperty(entryID, key, strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listProperties(entry *db.Entry) error {
+	properties, err := db.USERDB.Properties(entry.ID)
+	if err != nil {
+		return fmt.Errorf("Error getting properties: %v", err)
+	}
+	fmt.Printf("%sProperties of #%d %s%s\n", ui.Blue, entry.ID, entry.DisplayTitle(), ui.Reset)
+	if len(properties) == 0 {
+		fmt.Println("\t", "No properties set")
+	}
+	for _, property := range properties {
+		fmt.Printf("\t%s%s%s = %s %s(%s)%s\n", ui.Green, property.Key, ui.Reset, property.Value, ui.BrightBlack, property.Type, ui.Reset)
+	}
+	return nil
+}
+
+// Parse a filter like 'mood>=4 and project=apollo'
+func parseWhere(expr string) ([]propertyCondition, error) {
+	var conditions []propertyCondition
+	if strings.TrimSpace(expr) == "" {
+		return conditions, nil
+	}
+
+	for _, clause := range andRegex.Split(strings.TrimSpace(expr), -1) {
+		match := conditionRegex.FindStringSubmatch(clause)
+		if match == nil || match[3] == "" || strings.ContainsAny(match[3][:1], "<>=!") {
+			return nil, fmt.Errorf("can't understand %q, use key=value, key!=value, key>value, key>=value, key<value or key<=value", clause)
+		}
+		value := strings.Trim(match[3], `"'`)
+		conditions = append(conditions, propertyCondition{Key: match[1], Op: match[2], Value: value})
+	}
+	return conditions, nil
+}
+
+// Check an entry's properties against every condition.
+// Entries without the property never match.
+func matchesWhere(properties []db.Property, conditions []propertyCondition) bool {
+	for _, condition := range conditions {
+		matched := false
+		for _, property := range properties {
+			if property.Key == condition.Key {
+				matched = compareProperty(property, condition)
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func compareProperty(property db.Property, condition propertyCondition) bool {
+	cmp := 0
+	left, leftErr := strconv.ParseFloat(property.Value, 64)
+	right, rightErr := strconv.ParseFloat(condition.Value, 64)
+	switch {
+	case property.Type == db.NumberProperty && leftErr == nil && rightErr == nil:
+		if left < right {
+			cmp = -1
+		} else if left > right {
+			cmp = 1
+		}
+	case property.Type == db.BoolProperty:
+		cmp = strings.Compare(strings.ToLower(property.Value), strings.ToLower(condition.Value))
+	default:
+		cmp = strings.Compare(property.Value, condition.Value)
+	}
+
+	switch condition.Op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
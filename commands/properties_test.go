@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []propertyCondition
+	}{
+		{"", nil},
+		{"mood=4", []propertyCondition{{"mood", "=", "4"}}},
+		{"mood != 4", []propertyCondition{{"mood", "!=", "4"}}},
+		{"mood>4", []propertyCondition{{"mood", ">", "4"}}},
+		{"mood>=4", []propertyCondition{{"mood", ">=", "4"}}},
+		{"mood<4", []propertyCondition{{"mood", "<", "4"}}},
+		{"mood <= 4", []propertyCondition{{"mood", "<=", "4"}}},
+		{`project="apollo 11"`, []propertyCondition{{"project", "=", "apollo 11"}}},
+		{"mood>=4 and project=apollo AND done!=true", []propertyCondition{
+			{"mood", ">=", "4"},
+			{"project", "=", "apollo"},
+			{"done", "!=", "true"},
+		}},
+	}
+	for _, test := range tests {
+		got, err := parseWhere(test.expr)
+		if err != nil {
+			t.Errorf("parseWhere(%q) failed: %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseWhere(%q) = %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseWhereInvalid(t *testing.T) {
+	for _, expr := range []string{"mood", "mood=", "=4", "mood=>4", "mood==4", "mood<>4"} {
+		if _, err := parseWhere(expr); err == nil {
+			t.Errorf("parseWhere(%q) should fail", expr)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reviewTags     []string
+	reviewGroupBy  string
+	reviewPrevious int
+)
+
+var reviewCmd = &cobra.Command{
+	Use:       "review week|month|year",
+	Short:     "Create a volume from the entries of a week, month or year",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"week", "month", "year"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := createReview(args[0])
+		if err != nil {
+			return fmt.Errorf("Error creating review: %v", err)
+		}
+		fmt.Println("Volume Created Successfully")
+		return openEntry(volume, config.CONFIG.START_POS, false, false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+
+	// Flags
+	reviewCmd.Flags().StringSliceVarP(&reviewTags, "tags", "t", nil, "Only include entries with ANY of the provided tags")
+	reviewCmd.Flags().StringVarP(&reviewGroupBy, "group-by", "g", "day", "Group the volume by 'day', 'month' or 'tag'")
+	reviewCmd.Flags().IntVarP(&reviewPrevious, "previous", "p", 0, "Review the period this many periods ago instead of the current one")
+
+	// Completion
+	reviewCmd.RegisterFlagCompletionFunc("tags", completeTags)
+	reviewCmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions(volumeGroupings, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func createReview(period string) (*db.Entry, error) {
+	if !utils.SliceStrContains(volumeGroupings, reviewGroupBy) {
+		return nil, fmt.Errorf("unknown grouping %q, use %s", reviewGroupBy, strings.Join(volumeGroupings, ", "))
+	}
+
+	reviewTags = db.USERDB.ResolveAliases(reviewTags)
+	start, end, name := reviewWindow(period, time.Now(), reviewPrevious)
+	entries, err := db.USERDB.EntriesBetween(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []db.Entry
+	for _, entry := range entries {
+		// Volumes already repeat their originals
+		if entry.IsVolume {
+			continue
+		}
+		if len(reviewTags) > 0 && !hasAnyTag(entry, reviewTags) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no entries found for %s", name)
+	}
+
+	mergeList = selected
+	searchTags = reviewTags
+	volumeGroupBy = reviewGroupBy
+
+	return createVolume(strings.Fields(name), askStdin)
+}
+
+// Get the [start, end) window and volume name of a review period.
+// previous moves the window that many periods back from now.
+func reviewWindow(period string, now time.Time, previous int) (time.Time, time.Time, string) {
+	today := startOfDay(now)
+
+	switch period {
+	case "week":
+		// Weeks start on Monday
+		offset := (int(today.Weekday()) + 6) % 7
+		start := today.AddDate(0, 0, -offset-7*previous)
+		year, week := start.ISOWeek()
+		return start, start.AddDate(0, 0, 7), fmt.Sprintf("Review Week %d-W%02d", year, week)
+	case "month":
+		start := time.Date(today.Year(), today.Month()-time.Month(previous), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), fmt.Sprintf("Review Month %s", start.Format("2006-01 January"))
+	default:
+		start := time.Date(today.Year()-previous, 1, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(1, 0, 0), fmt.Sprintf("Review Year %d", start.Year())
+	}
+}
+
+func hasAnyTag(entry db.Entry, tags []string) bool {
+	for _, tag := range entry.Tags {
+		if utils.SliceStrContains(tags, tag.TagName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	"github.com/spf13/cobra"
+)
+
+// Relative dates for --since and --until, e.g. 7d, 2w, 3m or 1y ago
+var relativeDateRegex = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+var searchesCmd = &cobra.Command{
+	Use:   "searches",
+	Short: "List saved searches, run them with find @name",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		searches, err := db.USERDB.SavedSearches()
+		if err != nil {
+			return fmt.Errorf("Error listing searches: %v", err)
+		}
+		if len(searches) == 0 {
+			fmt.Println("No saved searches yet, save one with find [tags] --save [name]")
+			return nil
+		}
+		for _, search := range searches {
+			fmt.Printf("%s@%s%s %s\n", ui.Green, search.Name, ui.Reset, describeSearch(search))
+		}
+		return nil
+	},
+}
+
+var searchesDeleteCmd = &cobra.Command{
+	Use:               "delete [name]...",
+	Short:             "Delete saved searches",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeSearches,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, name := range args {
+			if err := db.USERDB.DeleteSearch(name); err != nil {
+				return fmt.Errorf("Error deleting search: %v", err)
+			}
+			fmt.Println("Deleted search", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchesCmd)
+	searchesCmd.AddCommand(searchesDeleteCmd)
+}
+
+// Capture the current find options as a saved search
+func currentSearch(name string) db.SavedSearch {
+	search := db.SavedSearch{
+		Name:          name,
+		Tags:          searchTags,
+		Inclusive:     inclusive,
+		Recursive:     recursive,
+		OriginalsOnly: originalsOnly,
+		StarredOnly:   starredOnly,
+		Where:         searchWhere,
+		Since:         searchSince,
+		Until:         searchUntil,
+	}
+	if search.Tags == nil {
+		search.Tags = []string{}
+	}
+	if ascending {
+		search.Sort = "asc"
+	} else if descending {
+		search.Sort = "desc"
+	}
+	return search
+}
+
+// Add a saved search's tags and options to the current find options.
+// Options given on the command line are kept.
+func applySearch(search *db.SavedSearch) {
+	searchTags = append(searchTags, search.Tags...)
+	inclusive = inclusive || search.Inclusive
+	recursive = recursive || search.Recursive
+	originalsOnly = originalsOnly || search.OriginalsOnly
+	starredOnly = starredOnly || search.StarredOnly
+	if !ascending && !descending {
+		ascending = search.Sort == "asc"
+		descending = search.Sort == "desc"
+	}
+	if search.Where != "" {
+		if searchWhere == "" {
+			searchWhere = search.Where
+		} else {
+			searchWhere += " and " + search.Where
+		}
+	}
+	if searchSince == "" {
+		searchSince = search.Since
+	}
+	if searchUntil == "" {
+		searchUntil = search.Until
+	}
+}
+
+// Split find arguments into tags, applying any @name saved searches among them
+func expandSearchArgs(args []string) error {
+	var tags []string
+	var saved []*db.SavedSearch
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			tags = append(tags, arg)
+			continue
+		}
+		search, err := db.USERDB.GetSearch(arg)
+		if err != nil {
+			return err
+		}
+		saved = append(saved, search)
+	}
+
+	searchTags = tags
+	searchWhere, searchSince, searchUntil = whereExpr, sinceExpr, untilExpr
+	for _, search := range saved {
+		applySearch(search)
+	}
+	return nil
+}
+
+func describeSearch(search db.SavedSearch) string {
+	parts := []string{strings.Join(search.Tags, " ")}
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{search.Inclusive, "--inclusive"},
+		{search.Recursive, "--recursive"},
+		{search.OriginalsOnly, "--originals-only"},
+		{search.StarredOnly, "--starred"},
+		{search.Sort == "asc", "--ascending"},
+		{search.Sort == "desc", "--descending"},
+	}
+	for _, flag := range flags {
+		if flag.set {
+			parts = append(parts, flag.name)
+		}
+	}
+	if search.Where != "" {
+		parts = append(parts, fmt.Sprintf("--where '%s'", search.Where))
+	}
+	if search.Since != "" {
+		parts = append(parts, "--since "+search.Since)
+	}
+	if search.Until != "" {
+		parts = append(parts, "--until "+search.Until)
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// Parse a date given as YYYY-MM-DD, today, yesterday or a relative 7d/2w/3m/1y.
+// With endOfDay the result is the last moment of that day.
+func parseDateExpr(expr string, now time.Time, endOfDay bool) (time.Time, error) {
+	day := startOfDay(now)
+	switch expr := strings.ToLower(strings.TrimSpace(expr)); {
+	case expr == "today":
+	case expr == "yesterday":
+		day = day.AddDate(0, 0, -1)
+	case relativeDateRegex.MatchString(expr):
+		match := relativeDateRegex.FindStringSubmatch(expr)
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "d":
+			day = day.AddDate(0, 0, -n)
+		case "w":
+			day = day.AddDate(0, 0, -7*n)
+		case "m":
+			day = monthsBefore(day, n)
+		default:
+			day = monthsBefore(day, 12*n)
+		}
+	default:
+		parsed, err := time.ParseInLocation("2006-01-02", expr, now.Location())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("can't understand date %q, use YYYY-MM-DD, today, yesterday or a relative 7d, 2w, 3m or 1y", expr)
+		}
+		day = parsed
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// Go back n months, keeping to the last day of shorter months
+// so 1m before March 31 is the end of February, not March 3
+func monthsBefore(day time.Time, n int) time.Time {
+	first := time.Date(day.Year(), day.Month()-time.Month(n), 1, 0, 0, 0, 0, day.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day.Day(), lastDay)-1)
+}
+
+// Complete tags, or saved searches once an argument starts with @
+func completeFindArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !strings.HasPrefix(toComplete, "@") {
+		return completeTags(cmd, args, toComplete)
+	}
+	return savedSearchNames("@"), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeSearches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return savedSearchNames(""), cobra.ShellCompDirectiveNoFileComp
+}
+
+// Get the names of all saved searches, each with prefix in front
+func savedSearchNames(prefix string) []string {
+	searches, err := db.USERDB.SavedSearches()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, search := range searches {
+		names = append(names, prefix+search.Name)
+	}
+	return names
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateExpr(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 15, 4, 5, 0, time.Local)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		expr     string
+		endOfDay bool
+		want     time.Time
+	}{
+		{"today", false, day(2024, time.March, 31)},
+		{" Today ", false, day(2024, time.March, 31)},
+		{"yesterday", false, day(2024, time.March, 30)},
+		{"7d", false, day(2024, time.March, 24)},
+		{"31d", false, day(2024, time.February, 29)},
+		{"2w", false, day(2024, time.March, 17)},
+		{"1m", false, day(2024, time.February, 29)},
+		{"3m", false, day(2023, time.December, 31)},
+		{"13m", false, day(2023, time.February, 28)},
+		{"1y", false, day(2023, time.March, 31)},
+		{"2024-02-29", false, day(2024, time.February, 29)},
+		{"today", true, day(2024, time.April, 1).Add(-time.Nanosecond)},
+		{"2023-12-31", true, day(2024, time.January, 1).Add(-time.Nanosecond)},
+	}
+	for _, test := range tests {
+		got, err := parseDateExpr(test.expr, now, test.endOfDay)
+		if err != nil {
+			t.Errorf("parseDateExpr(%q) failed: %v", test.expr, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseDateExpr(%q, endOfDay=%v) = %v, want %v", test.expr, test.endOfDay, got, test.want)
+		}
+	}
+}
+
+func TestParseDateExprLeapYear(t *testing.T) {
+	now := time.Date(2024, time.February, 29, 12, 0, 0, 0, time.Local)
+	got, err := parseDateExpr("1y", now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, time.February, 28, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("1y before %v = %v, want %v", now, got, want)
+	}
+}
+
+func TestParseDateExprInvalid(t *testing.T) {
+	now := time.Now()
+	for _, expr := range []string{"", "tomorrow", "7", "d7", "3x", "2024-02-30", "02-01-2024"} {
+		if _, err := parseDateExpr(expr, now, false); err == nil {
+			t.Errorf("parseDateExpr(%q) should fail", expr)
+		}
+	}
+}
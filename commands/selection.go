@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+)
+
+// One or more comma separated items like 3, 1-5, * or !2
+var selectionRegex = regexp.MustCompile(`^!?(\*|\d+(-\d+)?)(,!?(\*|\d+(-\d+)?))*,?$`)
+
+// Parse a selection of list numbers into 0-based indexes in list order.
+// Items can be numbers, ranges like 1-5 or * for everything, separated by
+// commas or spaces. Items starting with ! are left out, and a selection of
+// only ! items starts from the whole list, e.g. !3 is everything but 3.
+func parseSelection(args []string, count int) ([]int, error) {
+	selected := make(map[int]bool)
+	excluded := make(map[int]bool)
+	included, found := false, false
+
+	for _, arg := range args {
+		for _, item := range strings.Split(arg, ",") {
+			if item == "" {
+				continue
+			}
+			found = true
+			target := selected
+			if strings.HasPrefix(item, "!") {
+				target = excluded
+				item = item[1:]
+			} else {
+				included = true
+			}
+
+			from, to, err := selectionRange(item, count)
+			if err != nil {
+				return nil, err
+			}
+			for i := from; i <= to; i++ {
+				target[i] = true
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("select entries by number, e.g. 3, 1-5,8, * or !2")
+	}
+	if !included {
+		for i := 1; i <= count; i++ {
+			selected[i] = true
+		}
+	}
+	var indexes []int
+	for i := range selected {
+		if !excluded[i] {
+			indexes = append(indexes, i-1)
+		}
+	}
+	sort.Ints(indexes)
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+	return indexes, nil
+}
+
+// Get the first and last number of a selection item
+func selectionRange(item string, count int) (int, int, error) {
+	if item == "*" {
+		return 1, count, nil
+	}
+	start, end, isRange := strings.Cut(item, "-")
+	from, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid selection: %s", item)
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(end); err != nil {
+			return 0, 0, fmt.Errorf("invalid selection: %s", item)
+		}
+	}
+	if from > to {
+		from, to = to, from
+	}
+	if from < 1 || to > count {
+		return 0, 0, fmt.Errorf("invalid selection: %s, pick from 1-%d", item, count)
+	}
+	return from, to, nil
+}
+
+// Split prompt arguments into the leading selection and whatever follows it
+func splitSelection(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if !selectionRegex.MatchString(arg) {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// Get the selected entries of a list
+func selectEntries(entries []db.Entry, args []string) ([]db.Entry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the list is empty")
+	}
+	indexes, err := parseSelection(args, len(entries))
+	if err != nil {
+		return nil, err
+	}
+	var selected []db.Entry
+	for _, i := range indexes {
+		selected = append(selected, entries[i])
+	}
+	return selected, nil
+}
+
+// Get a list without the entries with the given ids
+func withoutEntries(entries []db.Entry, ids map[uint]bool) []db.Entry {
+	var kept []db.Entry
+	for _, entry := range entries {
+		if !ids[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+// Pager used when $PAGER isn't set
+const defaultPager = "less -R"
+
+// Color and style codes, which take up no room on screen
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+var showCmd = &cobra.Command{
+	Use:               "show [entry]",
+	Short:             "Print an entry or volume with its markdown rendered",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := db.USERDB.FindEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("Error finding entry: %v", err)
+		}
+		lines, err := utils.GetLines(entry.FilePath)
+		if err != nil {
+			return fmt.Errorf("Error reading entry: %v", err)
+		}
+
+		// Pipes and files get the markdown as written
+		fd := int(os.Stdout.Fd())
+		if !readline.IsTerminal(fd) {
+			fmt.Println(strings.Join(lines, "\n"))
+			return nil
+		}
+
+		width, height, err := readline.GetSize(fd)
+		if err != nil || width <= 0 {
+			width, height = 80, 0
+		}
+		rendered := append(showHeader(entry, min(width, 80)), ui.RenderMarkdown(trimBlankLines(bodyLines(lines)), min(width, 80))...)
+		output := strings.Join(rendered, "\n")
+		if height > 0 && screenRows(rendered, width) >= height {
+			return page(output)
+		}
+		fmt.Println(output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
+
+// Title, id, date and tags shown above the body
+func showHeader(entry *db.Entry, width int) []string {
+	var tags []string
+	for _, tag := range entry.Tags {
+		tags = append(tags, "#"+tag.TagName)
+	}
+	info := fmt.Sprintf("#%d | Created: %s", entry.ID, entry.CreatedAt.Format("01-02-2006"))
+	if len(tags) > 0 {
+		info += " | " + strings.Join(tags, " ")
+	}
+	return []string{
+		ui.EntryMarkers(*entry) + ui.Bold + ui.BrightBlue + entry.DisplayTitle() + ui.Reset,
+		ui.BrightBlack + info + ui.Reset,
+		ui.BrightBlack + strings.Repeat("═", width) + ui.Reset,
+		"",
+	}
+}
+
+// Count the terminal rows lines take up once long ones wrap
+func screenRows(lines []string, width int) int {
+	rows := 0
+	for _, line := range lines {
+		visible := readline.Runes{}.WidthAll([]rune(ansiRegex.ReplaceAllString(line, "")))
+		rows += max(1, (visible+width-1)/width)
+	}
+	return rows
+}
+
+// Show text through $PAGER, or print it if the pager can't run
+func page(text string) error {
+	pager := os.Getenv("PAGER")
+	if strings.TrimSpace(pager) == "" {
+		pager = defaultPager
+	}
+	fields := strings.Fields(pager)
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin = strings.NewReader(text + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, notFound := err.(*exec.Error); notFound {
+			fmt.Println(text)
+			return nil
+		}
+		return fmt.Errorf("Error running pager: %v", err)
+	}
+	return nil
+}
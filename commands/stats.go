@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsJSON  bool
+	statsWeeks int
+	statsTop   int
+)
+
+type journalStats struct {
+	Entries       int            `json:"entries"`
+	Volumes       int            `json:"volumes"`
+	Words         int            `json:"words"`
+	FirstEntry    *time.Time     `json:"first_entry,omitempty"`
+	AvgPerDay     float64        `json:"avg_per_day"`
+	AvgPerWeek    float64        `json:"avg_per_week"`
+	AvgPerMonth   float64        `json:"avg_per_month"`
+	PerDay        map[string]int `json:"per_day"`
+	PerWeek       map[string]int `json:"per_week"`
+	PerMonth      map[string]int `json:"per_month"`
+	CurrentStreak int            `json:"current_streak"`
+	LongestStreak int            `json:"longest_streak"`
+	TopTags       []db.TagCount  `json:"top_tags"`
+	TrendingTags  []tagTrend     `json:"trending_tags"`
+}
+
+type tagTrend struct {
+	TagName  string `json:"tag"`
+	Recent   int    `json:"recent"`
+	Previous int    `json:"previous"`
+}
+
+// Window used to compare recent tag usage against the one before it
+const trendDays = 30
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about your journal",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stats, err := collectStats(time.Now())
+		if err != nil {
+			return fmt.Errorf("Error collecting stats: %v", err)
+		}
+
+		if statsJSON {
+			out, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return fmt.Errorf("Error encoding stats: %v", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		printStats(stats, time.Now())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	// Flags
+	statsCmd.Flags().BoolVarP(&statsJSON, "json", "j", false, "Output the statistics as JSON")
+	statsCmd.Flags().IntVarP(&statsWeeks, "weeks", "w", 26, "Number of weeks shown in the calendar heatmap")
+	statsCmd.Flags().IntVarP(&statsTop, "top", "t", 10, "Number of tags shown in the most used and trending lists")
+}
+
+func collectStats(now time.Time) (*journalStats, error) {
+	entries, err := db.USERDB.AllEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &journalStats{
+		PerDay:   make(map[string]int),
+		PerWeek:  make(map[string]int),
+		PerMonth: make(map[string]int),
+	}
+
+	today := startOfDay(now)
+	recentStart := today.AddDate(0, 0, -trendDays+1)
+	previousStart := recentStart.AddDate(0, 0, -trendDays)
+	recentTags := make(map[string]int)
+	previousTags := make(map[string]int)
+
+	for _, entry := range entries {
+		if entry.IsVolume {
+			stats.Volumes++
+			continue
+		}
+		stats.Entries++
+		if stats.FirstEntry == nil {
+			created := entry.CreatedAt
+			stats.FirstEntry = &created
+		}
+
+		year, week := entry.CreatedAt.ISOWeek()
+		stats.PerDay[entry.CreatedAt.Format("2006-01-02")]++
+		stats.PerWeek[fmt.Sprintf("%d-W%02d", year, week)]++
+		stats.PerMonth[entry.CreatedAt.Format("2006-01")]++
+
+		for _, tag := range entry.Tags {
+			if !entry.CreatedAt.Before(recentStart) {
+				recentTags[tag.TagName]++
+			} else if !entry.CreatedAt.Before(previousStart) {
+				previousTags[tag.TagName]++
+			}
+		}
+
+		body, err := readBody(entry.FilePath)
+		if err != nil {
+			// A missing file shouldn't hide the rest of the stats
+			continue
+		}
+		stats.Words += utils.CountWords(body)
+	}
+
+	if stats.FirstEntry != nil {
+		days := today.Sub(startOfDay(*stats.FirstEntry)).Hours()/24 + 1
+		stats.AvgPerDay = float64(stats.Entries) / days
+		stats.AvgPerWeek = stats.AvgPerDay * 7
+		stats.AvgPerMonth = stats.AvgPerDay * 365.25 / 12
+	}
+
+	stats.CurrentStreak, stats.LongestStreak = streaks(stats.PerDay, today)
+
+	tagCounts, err := db.USERDB.TagCounts()
+	if err != nil {
+		return nil, err
+	}
+	if len(tagCounts) > statsTop {
+		tagCounts = tagCounts[:statsTop]
+	}
+	stats.TopTags = tagCounts
+	stats.TrendingTags = trending(recentTags, previousTags, statsTop)
+
+	return stats, nil
+}
+
+// Get the current and longest run of consecutive days with at least one entry.
+// The current streak survives until the end of today even if nothing was written yet.
+func streaks(perDay map[string]int, today time.Time) (int, int) {
+	var days []string
+	for day := range perDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	longest, run := 0, 0
+	var last time.Time
+	for _, day := range days {
+		date, err := time.ParseInLocation("2006-01-02", day, today.Location())
+		if err != nil {
+			continue
+		}
+		if run > 0 && date.Equal(last.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		last = date
+		if run > longest {
+			longest = run
+		}
+	}
+
+	current := 0
+	day := today
+	if perDay[day.Format("2006-01-02")] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for perDay[day.Format("2006-01-02")] > 0 {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return current, longest
+}
+
+// Get the tags whose usage grew the most between the previous and recent window
+func trending(recent map[string]int, previous map[string]int, limit int) []tagTrend {
+	var trends []tagTrend
+	for tag, count := range recent {
+		if count > previous[tag] {
+			trends = append(trends, tagTrend{TagName: tag, Recent: count, Previous: previous[tag]})
+		}
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		gainI := trends[i].Recent - trends[i].Previous
+		gainJ := trends[j].Recent - trends[j].Previous
+		if gainI != gainJ {
+			return gainI > gainJ
+		}
+		return trends[i].TagName < trends[j].TagName
+	})
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+	return trends
+}
+
+func printStats(stats *journalStats, now time.Time) {
+	fmt.Println(ui.Blue + ui.Bold + "JOURNAL STATS" + ui.Reset)
+	fmt.Println("")
+	fmt.Printf("%sEntries:%s %d   %sVolumes:%s %d   %sWords:%s %d\n",
+		ui.Magenta, ui.Reset, stats.Entries,
+		ui.Magenta, ui.Reset, stats.Volumes,
+		ui.Magenta, ui.Reset, stats.Words)
+	if stats.FirstEntry != nil {
+		fmt.Printf("%sFirst entry:%s %s\n", ui.Magenta, ui.Reset, stats.FirstEntry.Format("01/02/2006"))
+	}
+	fmt.Printf("%sPer day:%s %.2f   %sPer week:%s %.2f   %sPer month:%s %.2f\n",
+		ui.Magenta, ui.Reset, stats.AvgPerDay,
+		ui.Magenta, ui.Reset, stats.AvgPerWeek,
+		ui.Magenta, ui.Reset, stats.AvgPerMonth)
+
+	year, week := now.ISOWeek()
+	fmt.Printf("%sToday:%s %d   %sThis week:%s %d   %sThis month:%s %d\n",
+		ui.Magenta, ui.Reset, stats.PerDay[now.Format("2006-01-02")],
+		ui.Magenta, ui.Reset, stats.PerWeek[fmt.Sprintf("%d-W%02d", year, week)],
+		ui.Magenta, ui.Reset, stats.PerMonth[now.Format("2006-01")])
+	fmt.Printf("%sCurrent streak:%s %d days   %sLongest streak:%s %d days\n",
+		ui.Magenta, ui.Reset, stats.CurrentStreak,
+		ui.Magenta, ui.Reset, stats.LongestStreak)
+
+	fmt.Println("")
+	fmt.Println(ui.Green + "Most used tags" + ui.Reset)
+	if len(stats.TopTags) == 0 {
+		fmt.Println("\t", "No tags yet")
+	}
+	for _, tag := range stats.TopTags {
+		fmt.Printf("\t%-24s %d\n", tag.TagName, tag.Count)
+	}
+
+	fmt.Println("")
+	fmt.Printf("%sTrending tags (last %d days)%s\n", ui.Green, trendDays, ui.Reset)
+	if len(stats.TrendingTags) == 0 {
+		fmt.Println("\t", "Nothing trending")
+	}
+	for _, tag := range stats.TrendingTags {
+		fmt.Printf("\t%-24s %d (was %d)\n", tag.TagName, tag.Recent, tag.Previous)
+	}
+
+	fmt.Println("")
+	fmt.Println(heatmap(stats.PerDay, now, statsWeeks))
+}
+
+// Render a calendar of entry counts, one column per week and one row per weekday
+func heatmap(perDay map[string]int, now time.Time, weeks int) string {
+	if weeks < 1 {
+		weeks = 1
+	}
+	shades := []string{ui.BrightBlack + "·", ui.Green + "░", ui.Green + "▒", ui.BrightGreen + "▓", ui.BrightGreen + "█"}
+
+	today := startOfDay(now)
+	// Monday of the first displayed week
+	start := today.AddDate(0, 0, -(int(today.Weekday())+6)%7-7*(weeks-1))
+
+	var sb strings.Builder
+
+	// Month labels over the first week of each month
+	sb.WriteString("    ")
+	label := ""
+	for w := 0; w < weeks; w++ {
+		monday := start.AddDate(0, 0, 7*w)
+		if label == "" && (w == 0 || monday.Day() <= 7) {
+			label = monday.Format("Jan")
+		}
+		if label != "" {
+			sb.WriteString(label[:1])
+			label = label[1:]
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+	sb.WriteString("\n")
+
+	weekdays := []string{"Mon", "   ", "Wed", "   ", "Fri", "   ", "Sun"}
+	for d := 0; d < 7; d++ {
+		sb.WriteString(weekdays[d] + " ")
+		for w := 0; w < weeks; w++ {
+			day := start.AddDate(0, 0, 7*w+d)
+			if day.After(today) {
+				sb.WriteString(" ")
+				continue
+			}
+			count := perDay[day.Format("2006-01-02")]
+			if count >= len(shades) {
+				count = len(shades) - 1
+			}
+			sb.WriteString(shades[count])
+		}
+		sb.WriteString(ui.Reset + "\n")
+	}
+
+	sb.WriteString("    less ")
+	for _, shade := range shades {
+		sb.WriteString(shade)
+	}
+	sb.WriteString(ui.Reset + " more")
+	return sb.String()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
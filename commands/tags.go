@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/ui"
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "View and manage tags",
+}
+
+var tagsTreeCmd = &cobra.Command{
+	Use:               "tree [tag]",
+	Short:             "Show tags as a tree with entry counts rolled up per level",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeTags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := buildTagTree()
+		if err != nil {
+			return fmt.Errorf("Error building tag tree: %v", err)
+		}
+		if len(args) > 0 {
+			root = root.find(args[0])
+			if root == nil {
+				return fmt.Errorf("Tag %s does not exist", args[0])
+			}
+			fmt.Printf("%s%s%s (%d)\n", ui.Blue, root.path, ui.Reset, root.count)
+		}
+		printTagTree(root, "")
+		return nil
+	},
+}
+
+var tagsRenameCmd = &cobra.Command{
+	Use:               "rename [old] [new]",
+	Short:             "Rename a tag and every tag nested below it",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeTags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		renamed, err := db.USERDB.RenameTag(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("Error renaming tag: %v", err)
+		}
+		fmt.Printf("Renamed %d tag(s) from %s to %s\n", renamed, args[0], args[1])
+		return nil
+	},
+}
+
+var tagsAliasCmd = &cobra.Command{
+	Use:   "alias [alias] [tag]",
+	Short: "Make alias resolve to tag, or list all aliases",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("provide both an alias and the tag it stands for")
+		}
+		return nil
+	},
+	ValidArgsFunction: completeTags,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return listAliases()
+		}
+		err := db.USERDB.AddAlias(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("Error adding alias: %v", err)
+		}
+		fmt.Printf("%s now resolves to %s\n", args[0], db.USERDB.ResolveAliases(args[:1])[0])
+		return nil
+	},
+}
+
+var tagsUnaliasCmd = &cobra.Command{
+	Use:   "unalias [alias]",
+	Short: "Remove an alias",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := db.USERDB.RemoveAlias(args[0])
+		if err != nil {
+			return fmt.Errorf("Error removing alias: %v", err)
+		}
+		fmt.Println("Removed alias", args[0])
+		return nil
+	},
+}
+
+var tagsNormalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "Apply the TAG_NORMALIZATION config to all tags, merging any that collide",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changed, err := db.USERDB.NormalizeAllTags()
+		if err != nil {
+			return fmt.Errorf("Error normalizing tags: %v", err)
+		}
+		fmt.Printf("Normalized %d tag(s)\n", changed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagsCmd)
+	tagsCmd.AddCommand(tagsTreeCmd)
+	tagsCmd.AddCommand(tagsRenameCmd)
+	tagsCmd.AddCommand(tagsAliasCmd)
+	tagsCmd.AddCommand(tagsUnaliasCmd)
+	tagsCmd.AddCommand(tagsNormalizeCmd)
+}
+
+func listAliases() error {
+	aliases, err := db.USERDB.Aliases()
+	if err != nil {
+		return fmt.Errorf("Error listing aliases: %v", err)
+	}
+	if len(aliases) == 0 {
+		fmt.Println("No aliases yet")
+		return nil
+	}
+
+	byTag := make(map[string][]string)
+	var tags []string
+	for _, alias := range aliases {
+		if _, ok := byTag[alias.Tag.TagName]; !ok {
+			tags = append(tags, alias.Tag.TagName)
+		}
+		byTag[alias.Tag.TagName] = append(byTag[alias.Tag.TagName], alias.Alias)
+	}
+	for _, tag := range tags {
+		fmt.Printf("%s%s%s <- %s\n", ui.Green, tag, ui.Reset, strings.Join(byTag[tag], ", "))
+	}
+	return nil
+}
+
+// One level of a hierarchical tag
+type tagNode struct {
+	name     string
+	path     string
+	count    int
+	children map[string]*tagNode
+}
+
+func (n *tagNode) child(name string) *tagNode {
+	if n.children == nil {
+		n.children = make(map[string]*tagNode)
+	}
+	node, ok := n.children[name]
+	if !ok {
+		path := name
+		if n.path != "" {
+			path = n.path + db.TagSeparator + name
+		}
+		node = &tagNode{name: name, path: path}
+		n.children[name] = node
+	}
+	return node
+}
+
+func (n *tagNode) find(path string) *tagNode {
+	node := n
+	for _, part := range strings.Split(path, db.TagSeparator) {
+		next, ok := node.children[part]
+		if !ok {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// Build the tag hierarchy. Each level counts the entries tagged with it
+// or anything below it, so an entry is only counted once per level.
+func buildTagTree() (*tagNode, error) {
+	root := &tagNode{}
+
+	names, err := db.USERDB.TagNames("")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		node := root
+		for _, part := range strings.Split(name, db.TagSeparator) {
+			node = node.child(part)
+		}
+	}
+
+	entries, err := db.USERDB.AllEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsVolume {
+			continue
+		}
+		counted := make(map[*tagNode]bool)
+		for _, tag := range entry.Tags {
+			node := root
+			for _, part := range strings.Split(tag.TagName, db.TagSeparator) {
+				node = node.child(part)
+				if !counted[node] {
+					counted[node] = true
+					node.count++
+				}
+			}
+		}
+	}
+	return root, nil
+}
+
+func printTagTree(node *tagNode, indent string) {
+	var names []string
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		branch, nextIndent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", "    "
+		}
+		if indent == "" && node.path == "" {
+			branch, nextIndent = "", ""
+		}
+		fmt.Printf("%s%s%s%s%s (%d)\n", indent, branch, ui.Green, child.name, ui.Reset, child.count)
+		printTagTree(child, indent+nextIndent)
+	}
+}
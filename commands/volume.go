@@ -0,0 +1,611 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	"github.com/projectz-ro/journalz-ro/hooks"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"github.com/spf13/cobra"
+)
+
+// How volume update and volume add group the rebuilt volume,
+// "" keeps the grouping it was built with
+var volumeUpdateGroupBy string
+
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Rebuild volumes and add entries to them",
+}
+
+var volumeUpdateCmd = &cobra.Command{
+	Use:               "update [volume]",
+	Short:             "Rebuild a volume from its originals, keeping hand-written sections",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := findVolume(args[0])
+		if err != nil {
+			return err
+		}
+		if err := updateVolume(volume, nil, volumeUpdateGroupBy); err != nil {
+			return fmt.Errorf("Error updating volume: %v", err)
+		}
+		fmt.Println("Updated volume:", volume.FilePath)
+		return nil
+	},
+}
+
+var volumeAddCmd = &cobra.Command{
+	Use:               "add [volume] [entries]...",
+	Short:             "Add entries to the end of a volume",
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeEntries,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := findVolume(args[0])
+		if err != nil {
+			return err
+		}
+		var added []db.Entry
+		for _, ref := range args[1:] {
+			entry, err := db.USERDB.FindEntry(ref)
+			if err != nil {
+				return fmt.Errorf("Error finding entry: %v", err)
+			}
+			if entry.ID == volume.ID {
+				return fmt.Errorf("Error adding entries: a volume can't contain itself")
+			}
+			added = append(added, *entry)
+		}
+		if err := updateVolume(volume, added, volumeUpdateGroupBy); err != nil {
+			return fmt.Errorf("Error adding to volume: %v", err)
+		}
+		fmt.Printf("Added %d entries to volume: %s\n", len(added), volume.FilePath)
+		return nil
+	},
+}
+
+func init() {
+	db.RegisterMigration("volume-paths", separateVolumeFiles)
+
+	rootCmd.AddCommand(volumeCmd)
+	volumeCmd.AddCommand(volumeUpdateCmd)
+	volumeCmd.AddCommand(volumeAddCmd)
+
+	// Flags
+	volumeCmd.PersistentFlags().StringVarP(&volumeUpdateGroupBy, "group-by", "g", "", "Group the rebuilt volume into sections by day, month or tag")
+
+	// Completion
+	volumeCmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions(volumeGroupings, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func findVolume(ref string) (*db.Entry, error) {
+	volume, err := db.USERDB.FindEntry(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding volume: %v", err)
+	}
+	if !volume.IsVolume {
+		return nil, fmt.Errorf("%s is an entry, not a volume", ref)
+	}
+	return volume, nil
+}
+
+// Rebuild a volume's file from its originals with any added entries at the end.
+// Sections between keep markers stay after the entry they followed.
+// An empty groupBy keeps the grouping the volume was built with.
+func updateVolume(volume *db.Entry, added []db.Entry, groupBy string) error {
+	if groupBy == "" {
+		groupBy = volume.GroupBy
+	} else if !utils.SliceStrContains(volumeGroupings, groupBy) {
+		return fmt.Errorf("unknown grouping %q, use %s", groupBy, strings.Join(volumeGroupings, ", "))
+	}
+	lines, err := utils.GetLines(volume.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	order, kept := parseVolume(lines)
+
+	// Record the added entries, along with the originals of added volumes.
+	// A volume is never its own original.
+	self := map[uint]bool{volume.ID: true}
+	added = withoutEntries(added, self)
+	var newOriginals []db.Entry
+	for _, entry := range added {
+		newOriginals = append(newOriginals, entry)
+		newOriginals = append(newOriginals, withoutEntries(entry.Originals, self)...)
+	}
+	if err := db.USERDB.AddOriginals(volume, newOriginals); err != nil {
+		return err
+	}
+	var addedTags []string
+	for _, entry := range added {
+		for _, tag := range entry.Tags {
+			addedTags = append(addedTags, tag.TagName)
+		}
+	}
+	if err := db.USERDB.AddTags(volume, addedTags); err != nil {
+		return err
+	}
+
+	var addedIDs []uint
+	for _, entry := range added {
+		addedIDs = append(addedIDs, entry.ID)
+	}
+	entries, err := volumeEntries(volume, order, addedIDs)
+	if err != nil {
+		return err
+	}
+
+	// Keep the existing header so the volume keeps its date
+	header := volumeHeader(volume.Name, volume.CreatedAt)
+	for i, line := range lines {
+		if line == "## Contents" {
+			header = withoutKept(lines[:i])
+			for len(header) > 0 && strings.TrimSpace(header[len(header)-1]) == "" {
+				header = header[:len(header)-1]
+			}
+			header = append(header, "", "")
+			break
+		}
+	}
+	content := append([]string{}, header...)
+	if len(kept[0]) > 0 {
+		content = append(content, kept[0]...)
+		content = append(content, "")
+	}
+	body, err := volumeBody(volume.FilePath, entries, groupBy, nil, kept)
+	if err != nil {
+		return err
+	}
+	content = append(content, body...)
+
+	// Kept sections of entries no longer in the volume go at the end
+	present := make(map[uint]bool)
+	for _, entry := range entries {
+		present[entry.ID] = true
+	}
+	for _, id := range order {
+		if !present[id] && len(kept[id]) > 0 {
+			content = append(content, kept[id]...)
+			content = append(content, "")
+		}
+	}
+
+	before, err := utils.HashFile(volume.FilePath)
+	if err != nil {
+		before = ""
+	}
+	if err := utils.WriteLines(volume.FilePath, content); err != nil {
+		return err
+	}
+	if err := db.USERDB.SetVolumeGroupBy(volume, groupBy); err != nil {
+		return err
+	}
+	if err := carryAttachments(volume, entries); err != nil {
+		return fmt.Errorf("failed to copy attachments: %v", err)
+	}
+	if err := processEdit(volume.ID, before, false); err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	// Merging into an existing volume is still a merge
+	updated, err := db.USERDB.GetEntry(volume.ID)
+	if err != nil {
+		return err
+	}
+	if hookErr := hooks.Run(hooks.PostMerge, updated); hookErr != nil {
+		fmt.Println("Warning:", hookErr)
+	}
+	return nil
+}
+
+// Get the entries a volume is built from, in the order of its file.
+// Entries that are only in it through a merged volume are left out,
+// and entries not in the file yet go last, added ones at the very end.
+func volumeEntries(volume *db.Entry, order []uint, added []uint) ([]db.Entry, error) {
+	volume, err := db.USERDB.GetEntry(volume.ID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, original := range volume.Originals {
+		ids = append(ids, original.ID)
+	}
+	originals, err := db.USERDB.Entries(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	nested := make(map[uint]bool)
+	for _, original := range originals {
+		for _, inner := range original.Originals {
+			nested[inner.ID] = true
+		}
+	}
+
+	position := make(map[uint]int)
+	for i, id := range order {
+		position[id] = i + 1
+	}
+	for i, id := range added {
+		if _, ok := position[id]; !ok {
+			position[id] = len(order) + len(originals) + i + 1
+		}
+	}
+
+	var entries []db.Entry
+	for _, original := range originals {
+		if !nested[original.ID] || position[original.ID] > 0 {
+			entries = append(entries, original)
+		}
+	}
+	// Unplaced entries keep their oldest-first order in the middle
+	for i := range entries {
+		if position[entries[i].ID] == 0 {
+			position[entries[i].ID] = len(order) + i + 1
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return position[entries[i].ID] < position[entries[j].ID]
+	})
+	return entries, nil
+}
+
+func withoutKept(lines []string) []string {
+	var result []string
+	keeping := false
+	for _, line := range lines {
+		switch strings.TrimSpace(line) {
+		case keepStart:
+			keeping = true
+		case keepEnd:
+			keeping = false
+		default:
+			if !keeping {
+				result = append(result, line)
+			}
+		}
+	}
+	return result
+}
+
+// Find the order of the entry sections in a volume file and the kept sections
+// following each entry. Kept sections before the first entry are under id 0.
+func parseVolume(lines []string) ([]uint, map[uint][]string) {
+	var order []uint
+	kept := make(map[uint][]string)
+	current := uint(0)
+	keeping := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if keeping {
+			kept[current] = append(kept[current], line)
+			if trimmed == keepEnd {
+				keeping = false
+			}
+			continue
+		}
+		if trimmed == keepStart {
+			keeping = true
+			kept[current] = append(kept[current], line)
+			continue
+		}
+		if match := entryMarkerRegex.FindStringSubmatch(trimmed); match != nil {
+			id, _ := strconv.ParseUint(match[1], 10, 64)
+			current = uint(id)
+			order = append(order, current)
+		}
+	}
+	return order, kept
+}
+
+// Deepest markdown heading level
+const maxHeadingLevel = 6
+
+// Markers in volume files. Each entry's section starts with an entry marker
+// and text between keep markers survives volume update.
+const (
+	entryMarker = "<!-- entry:%d -->"
+	keepStart   = "<!-- keep -->"
+	keepEnd     = "<!-- /keep -->"
+)
+
+var (
+	headingLevelRegex = regexp.MustCompile(`^(#{1,6})(\s+.*)$`)
+	entryMarkerRegex  = regexp.MustCompile(`^<!-- entry:(\d+) -->$`)
+	anchorStripRegex  = regexp.MustCompile(`[^\p{L}\p{N}\s_-]`)
+	slugStripRegex    = regexp.MustCompile(`[^\p{L}\p{N}._-]`)
+)
+
+// Characters that can't be part of a volume name
+const unsafeVolumeChars = `/\:*?"<>|`
+
+// The date and name a volume file starts with
+func volumeHeader(name string, now time.Time) []string {
+	maxWidth := 80
+	return []string{
+		"",
+		fmt.Sprintf("%*s", maxWidth, now.Format("Monday")),
+		fmt.Sprintf("%*s", maxWidth, now.Format("01/02/2006")),
+		fmt.Sprintf("%*s", maxWidth, now.Format("15:04")),
+		fmt.Sprintf("# %s", name),
+		"",
+		"---",
+		"",
+		"",
+	}
+}
+
+// Build the body of a volume: a table of contents, then a section per entry
+// headed by its title, date, tags and a link back to its file.
+// Headings inside entries are demoted to sit below their section heading.
+// Kept hand-written sections are put back after the entry they followed.
+func volumeBody(volumePath string, entries []db.Entry, groupBy string, preferredTags []string, kept map[uint][]string) ([]string, error) {
+	groups := groupVolume(entries, groupBy, preferredTags)
+	entryLevel := 2
+	if len(groups) > 0 && groups[0].Title != "" {
+		entryLevel = 3
+	}
+
+	// Anchors are numbered in the order headings appear, starting with the contents
+	anchors := make(map[string]int)
+	headingAnchor("Contents", anchors)
+	groupAnchors := make([]string, len(groups))
+	entryAnchors := make(map[uint]string)
+	for i, group := range groups {
+		if group.Title != "" {
+			groupAnchors[i] = headingAnchor(group.Title, anchors)
+		}
+		for _, entry := range group.Entries {
+			entryAnchors[entry.ID] = headingAnchor(entry.DisplayTitle(), anchors)
+		}
+	}
+
+	contents := []string{"## Contents", ""}
+	var sections []string
+	for i, group := range groups {
+		indent := ""
+		if group.Title != "" {
+			contents = append(contents, fmt.Sprintf("- [%s](#%s)", group.Title, groupAnchors[i]))
+			sections = append(sections, "## "+group.Title, "")
+			indent = "  "
+		}
+		if len(group.SeeAlso) > 0 {
+			var refs []string
+			for _, entry := range group.SeeAlso {
+				refs = append(refs, fmt.Sprintf("[%s](#%s)", entry.DisplayTitle(), entryAnchors[entry.ID]))
+			}
+			sections = append(sections, "*See also:* "+strings.Join(refs, ", "), "")
+		}
+
+		for _, entry := range group.Entries {
+			title := entry.DisplayTitle()
+			contents = append(contents, fmt.Sprintf("%s- [%s](#%s)", indent, title, entryAnchors[entry.ID]))
+
+			lines, err := utils.GetLines(entry.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading original entries: %v", err)
+			}
+			sections = append(sections, fmt.Sprintf(entryMarker, entry.ID))
+			sections = append(sections, strings.Repeat("#", entryLevel)+" "+title, "")
+			sections = append(sections, entrySectionInfo(volumePath, entry), "")
+			body := trimBlankLines(withoutTitleHeading(bodyLines(lines), entry.Title))
+			sections = append(sections, demoteHeadings(body, entryLevel)...)
+			sections = append(sections, "", "---", "")
+			if len(kept[entry.ID]) > 0 {
+				sections = append(sections, kept[entry.ID]...)
+				sections = append(sections, "")
+			}
+		}
+	}
+
+	return append(append(contents, "", "---", ""), sections...), nil
+}
+
+// The date, tags and source link shown under an entry's section heading
+func entrySectionInfo(volumePath string, entry db.Entry) string {
+	parts := []string{"*" + entry.CreatedAt.Format("Monday 01/02/2006 15:04") + "*"}
+	if len(entry.Tags) > 0 {
+		var tags []string
+		for _, tag := range entry.Tags {
+			tags = append(tags, "`"+tag.TagName+"`")
+		}
+		parts = append(parts, strings.Join(tags, " "))
+	}
+	link := attachmentLink(volumePath, entry.FilePath)
+	if strings.ContainsAny(link, " ()") {
+		link = "<" + link + ">"
+	}
+	parts = append(parts, fmt.Sprintf("[%s](%s)", entry.Name, link))
+	return strings.Join(parts, " · ")
+}
+
+// Drop a leading heading that is the entry's title, as its section heading repeats it
+func withoutTitleHeading(body []string, title string) []string {
+	for i, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if match := headingRegex.FindStringSubmatch(trimmed); match != nil && strings.TrimSpace(match[1]) == title {
+			return body[i+1:]
+		}
+		return body
+	}
+	return body
+}
+
+// Push headings down so the highest one sits just below a section heading
+// of the given level. Headings in fenced code blocks are left alone.
+func demoteHeadings(body []string, sectionLevel int) []string {
+	highest := 0
+	inCode := false
+	for _, line := range body {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if match := headingLevelRegex.FindStringSubmatch(line); match != nil && !inCode {
+			if highest == 0 || len(match[1]) < highest {
+				highest = len(match[1])
+			}
+		}
+	}
+	shift := sectionLevel + 1 - highest
+	if highest == 0 || shift <= 0 {
+		return body
+	}
+
+	demoted := make([]string, 0, len(body))
+	inCode = false
+	for _, line := range body {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if match := headingLevelRegex.FindStringSubmatch(line); match != nil && !inCode {
+			level := min(len(match[1])+shift, maxHeadingLevel)
+			line = strings.Repeat("#", level) + match[2]
+		}
+		demoted = append(demoted, line)
+	}
+	return demoted
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Get the anchor markdown renderers give a heading, numbering repeats like they do
+func headingAnchor(heading string, seen map[string]int) string {
+	anchor := strings.ReplaceAll(anchorStripRegex.ReplaceAllString(strings.ToLower(strings.TrimSpace(heading)), ""), " ", "-")
+	count := seen[anchor]
+	seen[anchor]++
+	if count > 0 {
+		return fmt.Sprintf("%s-%d", anchor, count)
+	}
+	return anchor
+}
+
+// Give volumes that shared a file with a newer volume of the same name
+// a file of their own. The shared file holds the newest volume, which
+// keeps it, and the others are rebuilt from their originals.
+func separateVolumeFiles(c *db.DatabaseClient) error {
+	volumes, err := c.VolumesSharingFiles()
+	if err != nil {
+		return err
+	}
+	for i := range volumes {
+		volume := &volumes[i]
+		fileName, err := volumeFileName(volume.Name)
+		if err != nil {
+			fileName = fmt.Sprintf("volume-%d.md", volume.ID)
+		}
+		base := strings.TrimSuffix(fileName, ".md")
+		path := config.CONFIG.VOLUME_DIR + fileName
+		for n := 2; c.FileInUse(path) || utils.PathExists(path); n++ {
+			path = fmt.Sprintf("%s%s-%d.md", config.CONFIG.VOLUME_DIR, base, n)
+		}
+		if err := c.SetFilePath(volume, path); err != nil {
+			return err
+		}
+		volume.FilePath = path
+		// A volume that can't be rebuilt yet, e.g. as an original's file is
+		// missing, shouldn't stop the journal from opening
+		if err := updateVolume(volume, nil, ""); err != nil {
+			fmt.Printf("Volume '%s' now has its own file, rebuild it with: journalz-ro volume update %d (%v)\n", volume.Name, volume.ID, err)
+		}
+	}
+	return nil
+}
+
+// Asks the user a question and returns their answer
+type askFunc func(question string) string
+
+// Shared so piped answers aren't lost to a previous reader's buffer
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Ask on stdin, outside of the find prompt
+func askStdin(question string) string {
+	fmt.Print(question)
+	input, _ := stdinReader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+// Get the file name of a volume: its name lowercased, with dashes for spaces.
+// Names that could escape VOLUME_DIR or aren't valid file names are rejected.
+func volumeFileName(name string) (string, error) {
+	if strings.ContainsAny(name, unsafeVolumeChars) {
+		return "", fmt.Errorf("volume names can't contain any of %s", unsafeVolumeChars)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("volume names can't contain control characters")
+		}
+	}
+	if strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("volume names can't start with a dot")
+	}
+	slug := strings.Join(strings.Fields(strings.ToLower(name)), "-")
+	slug = slugStripRegex.ReplaceAllString(slug, "")
+	if strings.Trim(slug, ".-_") == "" {
+		return "", fmt.Errorf("give the volume a name with letters or numbers")
+	}
+	return slug + ".md", nil
+}
+
+// Pick a name for a new volume, asking what to do when a volume or file already has it.
+// Returns the name, its path, the volume it collides with and the chosen action:
+// "create", "overwrite" or "append".
+func chooseVolumeName(name string, ask askFunc) (string, string, *db.Entry, string, error) {
+	for {
+		name = strings.TrimSpace(name)
+		fileName, err := volumeFileName(name)
+		if err != nil {
+			return "", "", nil, "", err
+		}
+		path := config.CONFIG.VOLUME_DIR + fileName
+		existing, err := db.USERDB.FindVolume(name, path)
+		if err != nil {
+			return "", "", nil, "", err
+		}
+		if existing == nil && !utils.PathExists(path) {
+			return name, path, nil, "create", nil
+		}
+
+		question := fmt.Sprintf("%s already exists. [o]verwrite, [r]ename or [c]ancel? ", path)
+		if existing != nil {
+			path = existing.FilePath
+			question = fmt.Sprintf("Volume '%s' already exists. [o]verwrite, [r]ename, [a]ppend or [c]ancel? ", existing.Name)
+		}
+		switch strings.ToLower(ask(question)) {
+		case "o", "overwrite":
+			return name, path, existing, "overwrite", nil
+		case "a", "append":
+			if existing != nil {
+				return name, path, existing, "append", nil
+			}
+			return "", "", nil, "", fmt.Errorf("can't append to a file that isn't a volume")
+		case "r", "rename":
+			name = ask("New volume name: ")
+		default:
+			return "", "", nil, "", fmt.Errorf("cancelled, '%s' is already taken", name)
+		}
+	}
+}
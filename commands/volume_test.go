@@ -0,0 +1,371 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+)
+
+func TestVolumeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Trips", "trips.md"},
+		{"  Summer   Trips 2024 ", "summer-trips-2024.md"},
+		{"Trips (2)", "trips-2.md"},
+		{"Work & Play!", "work--play.md"},
+		{"v1.2_notes", "v1.2_notes.md"},
+		{"Café Réflexions", "café-réflexions.md"},
+		{"Ёлка", "ёлка.md"},
+		{"日記 2024", "日記-2024.md"},
+	}
+	for _, test := range tests {
+		got, err := volumeFileName(test.name)
+		if err != nil {
+			t.Errorf("volumeFileName(%q) failed: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("volumeFileName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestVolumeFileNameRejected(t *testing.T) {
+	names := []string{
+		"",
+		"   ",
+		"../escape",
+		"a/b",
+		`a\b`,
+		"c:drive",
+		"what?",
+		"star*",
+		`"quoted"`,
+		"<tag>",
+		"pipe|name",
+		".hidden",
+		"tab\tname",
+		"line\nbreak",
+		"!!!",
+		"---",
+		"🌍",
+	}
+	for _, name := range names {
+		if got, err := volumeFileName(name); err == nil {
+			t.Errorf("volumeFileName(%q) = %q, should be rejected", name, got)
+		}
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		wantOrder []uint
+		wantKept  map[uint][]string
+	}{
+		{
+			name:      "no markers",
+			lines:     []string{"# Old", "## Contents", "## one", "text"},
+			wantOrder: nil,
+			wantKept:  map[uint][]string{},
+		},
+		{
+			name: "kept after entries",
+			lines: []string{
+				"<!-- entry:2 -->", "## two",
+				"<!-- keep -->", "note on two", "<!-- /keep -->",
+				"<!-- entry:1 -->", "## one",
+			},
+			wantOrder: []uint{2, 1},
+			wantKept:  map[uint][]string{2: {"<!-- keep -->", "note on two", "<!-- /keep -->"}},
+		},
+		{
+			name: "kept before the first entry",
+			lines: []string{
+				"# Volume", "<!-- keep -->", "intro", "<!-- /keep -->",
+				"<!-- entry:5 -->", "## five",
+			},
+			wantOrder: []uint{5},
+			wantKept:  map[uint][]string{0: {"<!-- keep -->", "intro", "<!-- /keep -->"}},
+		},
+		{
+			name: "markers inside kept blocks are text",
+			lines: []string{
+				"<!-- entry:1 -->",
+				"<!-- keep -->", "<!-- entry:9 -->", "<!-- /keep -->",
+			},
+			wantOrder: []uint{1},
+			wantKept:  map[uint][]string{1: {"<!-- keep -->", "<!-- entry:9 -->", "<!-- /keep -->"}},
+		},
+	}
+	for _, test := range tests {
+		order, kept := parseVolume(test.lines)
+		if !reflect.DeepEqual(order, test.wantOrder) {
+			t.Errorf("%s: order = %v, want %v", test.name, order, test.wantOrder)
+		}
+		if !reflect.DeepEqual(kept, test.wantKept) {
+			t.Errorf("%s: kept = %q, want %q", test.name, kept, test.wantKept)
+		}
+	}
+}
+
+func TestWithoutKept(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"a", "<!-- keep -->", "b", "<!-- /keep -->", "c"}, []string{"a", "c"}},
+		{[]string{"a", "  <!-- keep -->", "b", "<!-- /keep -->  "}, []string{"a"}},
+		{[]string{"<!-- keep -->", "never closed"}, nil},
+	}
+	for _, test := range tests {
+		if got := withoutKept(test.lines); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("withoutKept(%q) = %q, want %q", test.lines, got, test.want)
+		}
+	}
+}
+
+func TestUpdateVolume(t *testing.T) {
+	tests := []struct {
+		name string
+		// Volume file after its header, with {one}, {two} and {three} for the entry ids
+		file  string
+		added bool
+		// Lines of the rebuilt file, in the order they should appear
+		want []string
+		// Lines that should be gone after the rebuild
+		gone []string
+	}{
+		{
+			name: "kept block survives",
+			file: "## Contents\n<!-- entry:{two} -->\n## two\n<!-- keep -->\nnote on two\n<!-- /keep -->\n<!-- entry:{one} -->\n## one\n",
+			want: []string{"## Contents", "<!-- entry:{two} -->", "note on two", "<!-- entry:{one} -->"},
+		},
+		{
+			name: "kept block of a removed entry moves to the end",
+			file: "## Contents\n<!-- entry:{three} -->\n## three\n<!-- keep -->\nnote on three\n<!-- /keep -->\n<!-- entry:{one} -->\n<!-- entry:{two} -->\n",
+			want: []string{"<!-- entry:{one} -->", "<!-- entry:{two} -->", "note on three"},
+			gone: []string{"<!-- entry:{three} -->"},
+		},
+		{
+			name: "kept block before the first entry",
+			file: "<!-- keep -->\nintro\n<!-- /keep -->\n## Contents\n<!-- entry:{one} -->\n<!-- entry:{two} -->\n",
+			want: []string{"# Volume", "intro", "## Contents", "<!-- entry:{one} -->", "<!-- entry:{two} -->"},
+		},
+		{
+			name: "old volume without markers",
+			file: "## Contents\n- [one](#one)\n## one\nold copy of one\n",
+			want: []string{"## Contents", "<!-- entry:{one} -->", "<!-- entry:{two} -->"},
+			gone: []string{"old copy of one"},
+		},
+		{
+			name:  "added entries go last",
+			file:  "## Contents\n<!-- entry:{two} -->\n<!-- entry:{one} -->\n",
+			added: true,
+			want:  []string{"<!-- entry:{two} -->", "<!-- entry:{one} -->", "<!-- entry:{three} -->"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestJournal(t)
+			one := testEntry(t, "one", time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local))
+			two := testEntry(t, "two", time.Date(2024, 1, 2, 9, 0, 0, 0, time.Local))
+			three := testEntry(t, "three", time.Date(2024, 1, 3, 9, 0, 0, 0, time.Local))
+			ids := strings.NewReplacer(
+				"{one}", fmt.Sprint(one.ID),
+				"{two}", fmt.Sprint(two.ID),
+				"{three}", fmt.Sprint(three.ID),
+			).Replace
+
+			path := config.CONFIG.VOLUME_DIR + "volume.md"
+			header := strings.Join(volumeHeader("Volume", time.Now()), "\n")
+			if err := os.WriteFile(path, []byte(header+"\n"+ids(test.file)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			volume, err := db.USERDB.InsertEntry("Volume", nil, []db.Entry{*one, *two}, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var added []db.Entry
+			if test.added {
+				added = []db.Entry{*three}
+			}
+
+			if err := updateVolume(volume, added, ""); err != nil {
+				t.Fatalf("updateVolume() failed: %v", err)
+			}
+			lines, err := utils.GetLines(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := -1
+			for _, want := range test.want {
+				want = ids(want)
+				i := slices.Index(lines, want)
+				if i < 0 {
+					t.Fatalf("%q missing from rebuilt volume:\n%s", want, strings.Join(lines, "\n"))
+				}
+				if i < last {
+					t.Fatalf("%q is out of order in rebuilt volume:\n%s", want, strings.Join(lines, "\n"))
+				}
+				last = i
+			}
+			for _, gone := range test.gone {
+				if slices.Contains(lines, ids(gone)) {
+					t.Errorf("%q should be gone from rebuilt volume:\n%s", ids(gone), strings.Join(lines, "\n"))
+				}
+			}
+		})
+	}
+}
+
+// Point the journal at a fresh directory and database for one test
+func useTestJournal(t *testing.T) {
+	t.Helper()
+	saved := config.CONFIG
+	dir := t.TempDir() + "/"
+	config.CONFIG = config.DEFAULT_CONFIG
+	config.CONFIG.ENTRY_DIR = dir
+	config.CONFIG.VOLUME_DIR = dir + "Volumes/"
+	config.CONFIG.HOOKS = nil
+	if err := os.MkdirAll(config.CONFIG.VOLUME_DIR, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InitializeDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.USERDB.Close()
+		config.CONFIG = saved
+	})
+}
+
+// Save an entry with a one line body, created at a given time
+func testEntry(t *testing.T, name string, created time.Time) *db.Entry {
+	t.Helper()
+	path := config.CONFIG.ENTRY_DIR + name + ".md"
+	lines := append(make([]string, config.CONFIG.START_POS-1), name+" body")
+	if err := utils.WriteLines(path, lines); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := db.USERDB.InsertEntry(name, []string{"test"}, nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.USERDB.DB.Model(entry).UpdateColumn("created_at", created).Error; err != nil {
+		t.Fatal(err)
+	}
+	entry.CreatedAt = created
+	return entry
+}
+
+func TestGroupVolumeByDate(t *testing.T) {
+	at := func(id uint, month time.Month, day int, hour int) db.Entry {
+		return db.Entry{ID: id, CreatedAt: time.Date(2024, month, day, hour, 0, 0, 0, time.Local)}
+	}
+	// Out of order, as after sort title, mv or volume add
+	entries := []db.Entry{
+		at(1, time.January, 2, 9),
+		at(2, time.February, 1, 9),
+		at(3, time.January, 1, 9),
+		at(4, time.January, 2, 8),
+		at(5, time.February, 1, 7),
+	}
+	tests := []struct {
+		groupBy string
+		want    map[string][]uint
+		titles  []string
+	}{
+		{
+			groupBy: "day",
+			titles:  []string{"Monday 01/01/2024", "Tuesday 01/02/2024", "Thursday 02/01/2024"},
+			want: map[string][]uint{
+				"Monday 01/01/2024":   {3},
+				"Tuesday 01/02/2024":  {1, 4},
+				"Thursday 02/01/2024": {2, 5},
+			},
+		},
+		{
+			groupBy: "month",
+			titles:  []string{"January 2024", "February 2024"},
+			want: map[string][]uint{
+				"January 2024":  {1, 3, 4},
+				"February 2024": {2, 5},
+			},
+		},
+	}
+	for _, test := range tests {
+		groups := groupVolume(entries, test.groupBy, nil)
+		var titles []string
+		for _, group := range groups {
+			titles = append(titles, group.Title)
+			var ids []uint
+			for _, entry := range group.Entries {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want[group.Title]) {
+				t.Errorf("%s: %s has entries %v, want %v", test.groupBy, group.Title, ids, test.want[group.Title])
+			}
+		}
+		if !reflect.DeepEqual(titles, test.titles) {
+			t.Errorf("%s: sections %q, want %q", test.groupBy, titles, test.titles)
+		}
+	}
+}
+
+func TestSeparateVolumeFiles(t *testing.T) {
+	useTestJournal(t)
+	one := testEntry(t, "one", time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local))
+	two := testEntry(t, "two", time.Date(2024, 1, 2, 9, 0, 0, 0, time.Local))
+
+	// Both volumes were saved to trips.md, the newer one last
+	path := config.CONFIG.VOLUME_DIR + "trips.md"
+	older, err := db.USERDB.InsertEntry("Trips (2)", nil, []db.Entry{*one}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := db.USERDB.InsertEntry("Trips", nil, []db.Entry{*two}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.USERDB.DB.Model(older).UpdateColumn("created_at", time.Date(2024, 2, 1, 9, 0, 0, 0, time.Local))
+	db.USERDB.DB.Model(newer).UpdateColumn("created_at", time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local))
+	newest := "hand edited newest volume"
+	if err := utils.WriteLines(path, []string{newest}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := separateVolumeFiles(&db.USERDB); err != nil {
+		t.Fatalf("separateVolumeFiles() failed: %v", err)
+	}
+
+	if kept, _ := db.USERDB.GetEntry(newer.ID); kept.FilePath != path {
+		t.Errorf("newest volume moved to %s, it should keep %s", kept.FilePath, path)
+	}
+	if lines, _ := utils.GetLines(path); !slices.Equal(lines, []string{newest}) {
+		t.Errorf("shared file was changed to %q", lines)
+	}
+	moved, _ := db.USERDB.GetEntry(older.ID)
+	if want := config.CONFIG.VOLUME_DIR + "trips-2.md"; moved.FilePath != want {
+		t.Errorf("older volume moved to %s, want %s", moved.FilePath, want)
+	}
+	lines, err := utils.GetLines(moved.FilePath)
+	if err != nil {
+		t.Fatalf("older volume wasn't rebuilt: %v", err)
+	}
+	if !slices.Contains(lines, fmt.Sprintf(entryMarker, one.ID)) || slices.Contains(lines, fmt.Sprintf(entryMarker, two.ID)) {
+		t.Errorf("older volume rebuilt with the wrong entries:\n%s", strings.Join(lines, "\n"))
+	}
+}
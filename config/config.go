@@ -8,10 +8,19 @@ import (
 )
 
 type Config struct {
-	ENTRY_DIR     string `json:"ENTRY_DIR"`
-	VOLUME_DIR    string `json:"VOLUME_DIR"`
-	INSERT_ON_NEW bool   `json:"INSERT_ON_NEW"`
-	START_POS     int    `json:"START_POS"`
+	ENTRY_DIR         string              `json:"ENTRY_DIR"`
+	VOLUME_DIR        string              `json:"VOLUME_DIR"`
+	INSERT_ON_NEW     bool                `json:"INSERT_ON_NEW"`
+	START_POS         int                 `json:"START_POS"`
+	HOOKS             map[string][]string `json:"HOOKS"`
+	TAG_NORMALIZATION TagNormalization    `json:"TAG_NORMALIZATION"`
+}
+
+// How tag names are cleaned up before they are stored or searched for
+type TagNormalization struct {
+	CASE_FOLD         bool   `json:"CASE_FOLD"`
+	SPACE_REPLACEMENT string `json:"SPACE_REPLACEMENT"`
+	STRIP_PUNCTUATION bool   `json:"STRIP_PUNCTUATION"`
 }
 
 var (
@@ -22,10 +31,15 @@ var (
 		VOLUME_DIR:    os.Getenv("HOME") + "/Documents/JournalZ-ro/Volumes/",
 		INSERT_ON_NEW: true,
 		START_POS:     8,
+		TAG_NORMALIZATION: TagNormalization{
+			CASE_FOLD:         true,
+			SPACE_REPLACEMENT: "-",
+			STRIP_PUNCTUATION: true,
+		},
 	}
 	CONFIG Config = DEFAULT_CONFIG
 
-	CommandsList []string = []string{"'new'", "'find'"}
+	CommandsList []string = []string{"'new'", "'find'", "'review'", "'stats'", "'completion'", "'tags'", "'backlinks'", "'attach'", "'set'", "'unset'", "'prompts'", "'searches'", "'volume'", "'delete'", "'merge'", "'show'"}
 )
 
 func LoadConfig() {
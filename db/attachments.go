@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+)
+
+// Where the attachments of an entry are kept
+func AttachmentDir(entryID uint) string {
+	return filepath.Join(config.CONFIG.ENTRY_DIR, "attachments", strconv.FormatUint(uint64(entryID), 10))
+}
+
+// Copy a file into an entry's attachments directory and record it.
+// The copy is renamed if the entry already has a file with that name.
+func (c *DatabaseClient) AddAttachment(entryID uint, source string) (*Attachment, error) {
+	dir := AttachmentDir(entryID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create attachments directory: %v", err)
+	}
+
+	name := filepath.Base(source)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; utils.PathExists(filepath.Join(dir, name)); i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+
+	dest := filepath.Join(dir, name)
+	if err := utils.CopyFile(source, dest); err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %v", source, err)
+	}
+
+	attachment := Attachment{EntryID: entryID, FileName: name, FilePath: dest}
+	if err := c.DB.Create(&attachment).Error; err != nil {
+		os.Remove(dest)
+		return nil, fmt.Errorf("failed to record attachment %s: %v", name, err)
+	}
+	return &attachment, nil
+}
+
+func (c *DatabaseClient) Attachments(entryID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := c.DB.Where("entry_id = ?", entryID).Order("id ASC").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %v", err)
+	}
+	return attachments, nil
+}
+
+func (c *DatabaseClient) removeAttachments(entryID uint) error {
+	if err := c.DB.Where("entry_id = ?", entryID).Delete(&Attachment{}).Error; err != nil {
+		return fmt.Errorf("failed to delete attachments: %v", err)
+	}
+	if err := os.RemoveAll(AttachmentDir(entryID)); err != nil {
+		return fmt.Errorf("failed to delete attachments directory: %v", err)
+	}
+	return nil
+}
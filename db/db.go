@@ -3,23 +3,126 @@ package db
 import (
 	"fmt"
 	"github.com/projectz-ro/journalz-ro/config"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 type Entry struct {
+	ID          uint    `gorm:"primary_key"`
+	Name        string  `gorm:"not null"`
+	FilePath    string  `gorm:"not null"`
+	Tags        []Tag   `gorm:"many2many:entry_tags;"`
+	Originals   []Entry `gorm:"many2many:entry_originals;joinTableForeignKey:entry_id;joinForeignKey:original_entry_id"`
+	Attachments []Attachment
+	Properties  []Property
+	Title       string    `gorm:"not null;default:''"`
+	WordCount   int       `gorm:"not null;default:0"`
+	ContentHash string    `gorm:"not null;default:''"`
+	Pinned      bool      `gorm:"not null;default:false"`
+	Starred     bool      `gorm:"not null;default:false"`
+	IsVolume    bool      `gorm:"not null;default:false"`
+	GroupBy     string    `gorm:"not null;default:''"` // How a volume is split into sections, "" for none
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// The title derived from an entry's body, or its name when it has none
+func (e Entry) DisplayTitle() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Name
+}
+
+// Another name that resolves to a canonical tag, e.g. k8s -> kubernetes
+type TagAlias struct {
+	ID        uint      `gorm:"primary_key"`
+	Alias     string    `gorm:"not null;unique"`
+	TagID     uint      `gorm:"not null"`
+	Tag       Tag       `gorm:"constraint:OnDelete:CASCADE;"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// A [[target]] written in the body of an entry.
+// TargetID is nil while the target doesn't match any entry.
+type Link struct {
 	ID        uint      `gorm:"primary_key"`
-	Name      string    `gorm:"not null"`
+	SourceID  uint      `gorm:"not null;index"`
+	Source    Entry     `gorm:"constraint:OnDelete:CASCADE;"`
+	TargetID  *uint     `gorm:"index"`
+	Target    string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// A file copied into an entry's attachments directory
+type Attachment struct {
+	ID        uint      `gorm:"primary_key"`
+	EntryID   uint      `gorm:"not null;index"`
+	FileName  string    `gorm:"not null"`
 	FilePath  string    `gorm:"not null"`
-	Tags      []Tag     `gorm:"many2many:entry_tags;"`
-	Originals []Entry   `gorm:"many2many:entry_originals;joinTableForeignKey:entry_id;joinForeignKey:original_entry_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// A typed key/value on an entry, e.g. mood=4 or project=apollo
+type Property struct {
+	ID        uint      `gorm:"primary_key"`
+	EntryID   uint      `gorm:"not null;uniqueIndex:idx_entry_property"`
+	Key       string    `gorm:"not null;uniqueIndex:idx_entry_property"`
+	Type      string    `gorm:"not null"`
+	Value     string    `gorm:"not null"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
+// A journaling prompt that was put into a new entry
+type PromptUse struct {
+	ID        uint      `gorm:"primary_key"`
+	Prompt    string    `gorm:"not null;index"`
+	Category  string    `gorm:"not null"`
+	EntryID   uint      `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Options of a find command kept under a name, run again with find @name.
+// Since and Until are kept as typed so relative ranges like 7d stay relative.
+type SavedSearch struct {
+	ID            uint      `gorm:"primary_key"`
+	Name          string    `gorm:"not null;unique"`
+	Tags          []string  `gorm:"serializer:json"`
+	Inclusive     bool      `gorm:"not null;default:false"`
+	Recursive     bool      `gorm:"not null;default:false"`
+	OriginalsOnly bool      `gorm:"not null;default:false"`
+	StarredOnly   bool      `gorm:"not null;default:false"`
+	Sort          string    `gorm:"not null;default:''"`
+	Where         string    `gorm:"not null;default:''"`
+	Since         string    `gorm:"not null;default:''"`
+	Until         string    `gorm:"not null;default:''"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// A one-time data migration that has already been applied
+type Migration struct {
+	ID        uint      `gorm:"primary_key"`
+	Name      string    `gorm:"not null;unique"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Metadata derived from the contents of an entry's file
+type EntryContent struct {
+	Hash       string
+	Title      string
+	WordCount  int
+	InlineTags []string
+	Links      []string
+}
+
 type Tag struct {
 	ID        uint      `gorm:"primary_key"`
 	TagName   string    `gorm:"not null;unique"`
@@ -27,6 +130,11 @@ type Tag struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
+type TagCount struct {
+	TagName string `json:"tag"`
+	Count   int    `json:"count"`
+}
+
 type DatabaseClient struct {
 	DB *gorm.DB
 }
@@ -40,11 +148,52 @@ func InitializeDB() error {
 	}
 
 	// Run AutoMigrate
-	if err := db.AutoMigrate(&Entry{}, &Tag{}); err != nil {
+	if err := db.AutoMigrate(&Entry{}, &Tag{}, &TagAlias{}, &Link{}, &Attachment{}, &Property{}, &PromptUse{}, &SavedSearch{}, &Migration{}); err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
 	}
 
 	USERDB.DB = db
+
+	if err := USERDB.runMigrations(); err != nil {
+		return fmt.Errorf("failed to migrate data: %v", err)
+	}
+	return nil
+}
+
+type migration struct {
+	name string
+	run  func(c *DatabaseClient) error
+}
+
+// Data migrations in the order they are applied, each runs once per database
+var migrations = []migration{
+	{"normalize-tags", func(c *DatabaseClient) error {
+		_, err := c.NormalizeAllTags()
+		return err
+	}},
+	{"volume-names", (*DatabaseClient).uniqueVolumeNames},
+}
+
+// Add a data migration that needs more than the database, run after the
+// built in ones. Must be called before InitializeDB.
+func RegisterMigration(name string, run func(c *DatabaseClient) error) {
+	migrations = append(migrations, migration{name, run})
+}
+
+func (c *DatabaseClient) runMigrations() error {
+	for _, migration := range migrations {
+		var applied int64
+		c.DB.Model(&Migration{}).Where("name = ?", migration.name).Count(&applied)
+		if applied > 0 {
+			continue
+		}
+		if err := migration.run(c); err != nil {
+			return fmt.Errorf("%s: %v", migration.name, err)
+		}
+		if err := c.DB.Create(&Migration{Name: migration.name}).Error; err != nil {
+			return fmt.Errorf("%s: %v", migration.name, err)
+		}
+	}
 	return nil
 }
 
@@ -63,14 +212,96 @@ func (c *DatabaseClient) InsertEntry(name string, tags []string, originals []Ent
 		Tags:      newTags,
 		Originals: originals,
 		FilePath:  filepath,
+		IsVolume:  len(originals) > 0,
 	}
 
 	if err := c.DB.Create(&entry).Error; err != nil {
 		return nil, fmt.Errorf("failed to create entry: %v", err)
 	}
+	if err := c.resolveLinksTo(&entry); err != nil {
+		return nil, err
+	}
 	return &entry, nil
 }
 
+func (c *DatabaseClient) GetEntry(id uint) (*Entry, error) {
+	var entry Entry
+	if err := c.DB.Preload("Tags").Preload("Originals").First(&entry, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find entry: %v", err)
+	}
+	return &entry, nil
+}
+
+// Store the metadata derived from an entry's file.
+// UpdatedAt only moves when the file was actually edited.
+func (c *DatabaseClient) UpdateEntryContent(entry *Entry, content EntryContent, edited bool) error {
+	updates := map[string]interface{}{
+		"content_hash": content.Hash,
+		"title":        content.Title,
+		"word_count":   content.WordCount,
+	}
+	if edited {
+		updates["updated_at"] = time.Now()
+	}
+	if err := c.DB.Model(entry).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to update entry: %v", err)
+	}
+
+	if len(content.InlineTags) > 0 {
+		newTags := c.batchInsertTags(content.InlineTags)
+		if err := c.DB.Model(entry).Omit("updated_at").Association("Tags").Append(newTags); err != nil {
+			return fmt.Errorf("failed to add inline tags: %v", err)
+		}
+	}
+	return c.setLinks(entry, content.Links)
+}
+
+// Add tags to an entry, creating any that don't exist yet
+func (c *DatabaseClient) AddTags(entry *Entry, names []string) error {
+	newTags := c.batchInsertTags(names)
+	if len(newTags) == 0 {
+		return nil
+	}
+	if err := c.DB.Model(entry).Omit("updated_at").Association("Tags").Append(newTags); err != nil {
+		return fmt.Errorf("failed to add tags: %v", err)
+	}
+	return nil
+}
+
+// Remove tags from an entry. The tags themselves are kept.
+func (c *DatabaseClient) RemoveTags(entry *Entry, names []string) error {
+	names = c.ResolveAliases(names)
+	if len(names) == 0 {
+		return nil
+	}
+	var tags []Tag
+	if err := c.DB.Where("tag_name IN (?)", names).Find(&tags).Error; err != nil {
+		return fmt.Errorf("failed to find tags: %v", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := c.DB.Model(entry).Association("Tags").Delete(tags); err != nil {
+		return fmt.Errorf("failed to remove tags: %v", err)
+	}
+	return nil
+}
+
+// Pinned entries are always listed first
+func (c *DatabaseClient) SetPinned(id uint, pinned bool) error {
+	if err := c.DB.Model(&Entry{}).Where("id = ?", id).UpdateColumn("pinned", pinned).Error; err != nil {
+		return fmt.Errorf("failed to pin entry: %v", err)
+	}
+	return nil
+}
+
+func (c *DatabaseClient) SetStarred(id uint, starred bool) error {
+	if err := c.DB.Model(&Entry{}).Where("id = ?", id).UpdateColumn("starred", starred).Error; err != nil {
+		return fmt.Errorf("failed to star entry: %v", err)
+	}
+	return nil
+}
+
 func (c *DatabaseClient) DeleteEntry(id uint) error {
 	var entry Entry
 	if err := c.DB.First(&entry, id).Error; err != nil {
@@ -86,12 +317,166 @@ func (c *DatabaseClient) DeleteEntry(id uint) error {
 	if err := c.DB.Delete(&entry).Error; err != nil {
 		return fmt.Errorf("failed to delete entry: %v", err)
 	}
+	if err := c.breakLinksTo(entry.ID); err != nil {
+		return err
+	}
+	if err := c.removeAttachments(entry.ID); err != nil {
+		return err
+	}
+	if err := c.DB.Where("entry_id = ?", entry.ID).Delete(&Property{}).Error; err != nil {
+		return fmt.Errorf("failed to delete properties: %v", err)
+	}
+	return nil
+}
+
+// Get the entries with the given ids, oldest first
+func (c *DatabaseClient) Entries(ids []uint) ([]Entry, error) {
+	var entries []Entry
+	if len(ids) == 0 {
+		return entries, nil
+	}
+	err := c.DB.Preload("Tags").Preload("Originals").Where("id IN (?)", ids).Order("created_at ASC").Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %v", err)
+	}
+	return entries, nil
+}
+
+// Record more originals for a volume
+func (c *DatabaseClient) AddOriginals(volume *Entry, originals []Entry) error {
+	if len(originals) == 0 {
+		return nil
+	}
+	if err := c.DB.Model(volume).Omit("updated_at").Association("Originals").Append(originals); err != nil {
+		return fmt.Errorf("failed to add originals: %v", err)
+	}
 	return nil
 }
 
+// Get every entry, oldest first
+func (c *DatabaseClient) AllEntries() ([]Entry, error) {
+	var entries []Entry
+	err := c.DB.Preload("Tags").Preload("Originals").Order("created_at ASC").Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %v", err)
+	}
+	return entries, nil
+}
+
+// Get the entries whose derived metadata has never been stored,
+// e.g. ones written before titles and word counts existed
+func (c *DatabaseClient) EntriesWithoutContent() ([]Entry, error) {
+	var entries []Entry
+	err := c.DB.Preload("Originals").Where("content_hash = ''").Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %v", err)
+	}
+	return entries, nil
+}
+
+// Get every tag with the number of entries using it, most used first.
+// Volumes are left out as they only repeat the tags of their originals.
+func (c *DatabaseClient) TagCounts() ([]TagCount, error) {
+	var counts []TagCount
+	err := c.DB.Table("entry_tags").
+		Select("tags.tag_name AS tag_name, COUNT(*) AS count").
+		Joins("JOIN tags ON entry_tags.tag_id = tags.id").
+		Where("entry_tags.entry_id NOT IN (?)", c.DB.Model(&Entry{}).Select("id").Where("is_volume")).
+		Group("tags.tag_name").
+		Order("count DESC, tags.tag_name ASC").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %v", err)
+	}
+	return counts, nil
+}
+
+// Get the names of tags starting with prefix, most used first
+func (c *DatabaseClient) TagNames(prefix string) ([]string, error) {
+	var names []string
+	prefix = NormalizeTag(prefix)
+	err := c.DB.Table("tags").
+		Select("tags.tag_name").
+		Joins("LEFT JOIN entry_tags ON entry_tags.tag_id = tags.id").
+		Where("tags.tag_name LIKE ? ESCAPE '\\'", escapeLike(prefix)+"%").
+		Group("tags.id").
+		Order("COUNT(entry_tags.entry_id) DESC, tags.tag_name ASC").
+		Pluck("tags.tag_name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag names: %v", err)
+	}
+	return names, nil
+}
+
+func (c *DatabaseClient) TagExists(name string) bool {
+	var count int64
+	name = NormalizeTag(name)
+	c.DB.Model(&Tag{}).Where("tag_name = ?", name).Count(&count)
+	return count > 0
+}
+
+// Get existing tags close to name, closest and then most used first.
+// A tag is close when it is a few edits away or one name starts with the other.
+func (c *DatabaseClient) SuggestTags(name string, limit int) ([]string, error) {
+	name = NormalizeTag(name)
+	names, err := c.TagNames("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow more typos in longer names
+	maxDistance := 1
+	if len([]rune(name)) > 5 {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, tagName := range names {
+		if tagName == name {
+			continue
+		}
+		distance := utils.Levenshtein(name, tagName)
+		isPrefix := len([]rune(name)) >= 3 &&
+			(strings.HasPrefix(tagName, name) || strings.HasPrefix(name, tagName))
+		if distance <= maxDistance || isPrefix {
+			candidates = append(candidates, candidate{tagName, distance})
+		}
+	}
+	// Stable so equally close tags stay in order of use
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []string
+	for _, cand := range candidates {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, cand.name)
+	}
+	return suggestions, nil
+}
+
+// Get all entries created within [start, end), oldest first
+func (c *DatabaseClient) EntriesBetween(start time.Time, end time.Time) ([]Entry, error) {
+	var entries []Entry
+	err := c.DB.Preload("Tags").Preload("Originals").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Order("created_at ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries between %v and %v: %v", start, end, err)
+	}
+	return entries, nil
+}
+
 func (c *DatabaseClient) batchInsertTags(tags []string) []Tag {
 	var insertedTags []Tag
-	for _, tagName := range tags {
+	for _, tagName := range c.ResolveAliases(tags) {
 		var tag Tag
 		if err := c.DB.Where("tag_name = ?", tagName).First(&tag).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Find an entry by its id or name. Entry names may leave off ".md".
+// Ids win over names, so a volume called "2024" needs its id once entry 2024 exists.
+func (c *DatabaseClient) FindEntry(ref string) (*Entry, error) {
+	ref = strings.TrimSpace(ref)
+
+	var entries []Entry
+	if id, err := strconv.ParseUint(strings.TrimPrefix(ref, "#"), 10, 64); err == nil {
+		err := c.DB.Preload("Tags").Preload("Originals").Where("id = ?", id).Limit(1).Find(&entries).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to find entry %s: %v", ref, err)
+		}
+		if len(entries) > 0 {
+			return &entries[0], nil
+		}
+	}
+
+	err := c.DB.Preload("Tags").Preload("Originals").
+		Where("name = ? OR name = ?", ref, ref+".md").
+		Order("created_at DESC").
+		Limit(1).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry %s: %v", ref, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entry or volume called %s", ref)
+	}
+	return &entries[0], nil
+}
+
+// Get the entries linking to an entry
+func (c *DatabaseClient) Backlinks(id uint) ([]Entry, error) {
+	var entries []Entry
+	err := c.DB.Preload("Tags").
+		Where("id IN (?)", c.DB.Model(&Link{}).Select("source_id").Where("target_id = ?", id)).
+		Order("created_at ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlinks: %v", err)
+	}
+	return entries, nil
+}
+
+// Get every link whose target doesn't match an entry
+func (c *DatabaseClient) BrokenLinks() ([]Link, error) {
+	var links []Link
+	err := c.DB.Preload("Source").Where("target_id IS NULL").Order("source_id ASC").Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get broken links: %v", err)
+	}
+	return links, nil
+}
+
+// Replace the links written in an entry's body
+func (c *DatabaseClient) setLinks(entry *Entry, targets []string) error {
+	if err := c.DB.Where("source_id = ?", entry.ID).Delete(&Link{}).Error; err != nil {
+		return fmt.Errorf("failed to clear links: %v", err)
+	}
+
+	for _, target := range targets {
+		link := Link{SourceID: entry.ID, Target: target}
+		if linked, err := c.FindEntry(target); err == nil && linked.ID != entry.ID {
+			link.TargetID = &linked.ID
+		}
+		if err := c.DB.Create(&link).Error; err != nil {
+			return fmt.Errorf("failed to add link to %s: %v", target, err)
+		}
+	}
+	return nil
+}
+
+// Point links that were waiting for a new entry at it
+func (c *DatabaseClient) resolveLinksTo(entry *Entry) error {
+	refs := []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		"#" + strconv.FormatUint(uint64(entry.ID), 10),
+		entry.Name,
+		strings.TrimSuffix(entry.Name, ".md"),
+	}
+	err := c.DB.Model(&Link{}).
+		Where("target_id IS NULL AND target IN (?)", refs).
+		Update("target_id", entry.ID).Error
+	if err != nil {
+		return fmt.Errorf("failed to resolve links: %v", err)
+	}
+	return nil
+}
+
+// Drop the links of a deleted entry and mark links to it as broken
+func (c *DatabaseClient) breakLinksTo(id uint) error {
+	if err := c.DB.Where("source_id = ?", id).Delete(&Link{}).Error; err != nil {
+		return fmt.Errorf("failed to remove links: %v", err)
+	}
+	if err := c.DB.Model(&Link{}).Where("target_id = ?", id).Update("target_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to break links: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Punctuation that survives STRIP_PUNCTUATION, so names like c++, c# or node.js keep their meaning
+const keptPunctuation = "-_.+#&"
+
+// Clean up a tag name according to the TAG_NORMALIZATION config.
+// Returns "" when nothing usable is left.
+func NormalizeTag(name string) string {
+	policy := config.CONFIG.TAG_NORMALIZATION
+
+	name = norm.NFC.String(name)
+	if policy.CASE_FOLD {
+		name = cases.Fold().String(name)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(name, TagSeparator) {
+		if policy.STRIP_PUNCTUATION {
+			part = strings.Map(func(r rune) rune {
+				if (unicode.IsPunct(r) || unicode.IsSymbol(r)) && !strings.ContainsRune(keptPunctuation, r) {
+					return -1
+				}
+				return r
+			}, part)
+		}
+		part = strings.Join(strings.Fields(part), policy.SPACE_REPLACEMENT)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, TagSeparator)
+}
+
+// Apply the current normalization policy to every stored tag and alias.
+// Tags that collide under the policy are merged. Returns the number of tags changed.
+func (c *DatabaseClient) NormalizeAllTags() (int, error) {
+	var tags []Tag
+	if err := c.DB.Order("id ASC").Find(&tags).Error; err != nil {
+		return 0, fmt.Errorf("failed to get tags: %v", err)
+	}
+
+	changed := 0
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		for _, tag := range tags {
+			target := NormalizeTag(tag.TagName)
+			if target == "" || target == tag.TagName {
+				continue
+			}
+			if err := renameOrMergeTag(tx, tag, target); err != nil {
+				return err
+			}
+			changed++
+		}
+
+		var aliases []TagAlias
+		if err := tx.Preload("Tag").Order("id ASC").Find(&aliases).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, alias := range aliases {
+			target := NormalizeTag(alias.Alias)
+			// Aliases that now collide with their tag or another alias are redundant
+			if target == "" || target == alias.Tag.TagName || seen[target] {
+				if err := tx.Delete(&alias).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			seen[target] = true
+			if target != alias.Alias {
+				if err := tx.Model(&alias).Update("alias", target).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to normalize tags: %v", err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+)
+
+func (c *DatabaseClient) RecordPromptUse(prompt string, category string, entryID uint) error {
+	use := PromptUse{Prompt: prompt, Category: category, EntryID: entryID}
+	if err := c.DB.Create(&use).Error; err != nil {
+		return fmt.Errorf("failed to record prompt use: %v", err)
+	}
+	return nil
+}
+
+// Get how recently each of the given prompts was used, as the id of its latest use.
+// Higher is more recent and unused prompts are left out.
+func (c *DatabaseClient) PromptsLastUsed(prompts []string) (map[string]uint, error) {
+	var rows []struct {
+		Prompt string
+		LastID uint
+	}
+	err := c.DB.Model(&PromptUse{}).
+		Select("prompt, MAX(id) AS last_id").
+		Where("prompt IN (?)", prompts).
+		Group("prompt").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt history: %v", err)
+	}
+
+	lastUsed := make(map[string]uint)
+	for _, row := range rows {
+		lastUsed[row.Prompt] = row.LastID
+	}
+	return lastUsed, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Property types, inferred from the value when it is set
+const (
+	NumberProperty = "number"
+	BoolProperty   = "bool"
+	StringProperty = "string"
+)
+
+// Infer the type of a raw property value
+func PropertyType(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return NumberProperty
+	}
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return BoolProperty
+	}
+	return StringProperty
+}
+
+// Set a property on an entry, replacing any value it had
+func (c *DatabaseClient) SetProperty(entryID uint, key string, value string) (*Property, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("property name cannot be empty")
+	}
+
+	var properties []Property
+	if err := c.DB.Where("entry_id = ? AND key = ?", entryID, key).Limit(1).Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to find property %s: %v", key, err)
+	}
+	property := Property{EntryID: entryID, Key: key}
+	if len(properties) > 0 {
+		property = properties[0]
+	}
+	property.Type = PropertyType(value)
+	property.Value = value
+
+	if err := c.DB.Save(&property).Error; err != nil {
+		return nil, fmt.Errorf("failed to set property %s: %v", key, err)
+	}
+	return &property, nil
+}
+
+func (c *DatabaseClient) UnsetProperty(entryID uint, key string) error {
+	result := c.DB.Where("entry_id = ? AND key = ?", entryID, key).Delete(&Property{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unset property %s: %v", key, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("entry has no property %s", key)
+	}
+	return nil
+}
+
+func (c *DatabaseClient) Properties(entryID uint) ([]Property, error) {
+	var properties []Property
+	if err := c.DB.Where("entry_id = ?", entryID).Order("key ASC").Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to get properties: %v", err)
+	}
+	return properties, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Save a search under its name, replacing any search already saved with that name
+func (c *DatabaseClient) SaveSearch(search SavedSearch) error {
+	search.Name = searchName(search.Name)
+	if search.Name == "" || strings.ContainsAny(search.Name, " \t") {
+		return fmt.Errorf("search names can't be empty or contain spaces")
+	}
+
+	var existing []SavedSearch
+	if err := c.DB.Where("name = ?", search.Name).Limit(1).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to save search %s: %v", search.Name, err)
+	}
+	if len(existing) > 0 {
+		search.ID = existing[0].ID
+		search.CreatedAt = existing[0].CreatedAt
+	}
+	if err := c.DB.Save(&search).Error; err != nil {
+		return fmt.Errorf("failed to save search %s: %v", search.Name, err)
+	}
+	return nil
+}
+
+func (c *DatabaseClient) GetSearch(name string) (*SavedSearch, error) {
+	name = searchName(name)
+	var searches []SavedSearch
+	if err := c.DB.Where("name = ?", name).Limit(1).Find(&searches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get search %s: %v", name, err)
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("no saved search named %s", name)
+	}
+	return &searches[0], nil
+}
+
+func (c *DatabaseClient) SavedSearches() ([]SavedSearch, error) {
+	var searches []SavedSearch
+	if err := c.DB.Order("name ASC").Find(&searches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %v", err)
+	}
+	return searches, nil
+}
+
+func (c *DatabaseClient) DeleteSearch(name string) error {
+	name = searchName(name)
+	result := c.DB.Where("name = ?", name).Delete(&SavedSearch{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete search %s: %v", name, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no saved search named %s", name)
+	}
+	return nil
+}
+
+// Names are matched without the @ and case-insensitively, as the find prompt lowercases input
+func searchName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+}
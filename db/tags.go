@@ -0,0 +1,181 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+	"gorm.io/gorm"
+)
+
+// Separates the levels of hierarchical tags, e.g. work/projectx/design
+const TagSeparator = "/"
+
+// Get the tags nested anywhere below name
+func (c *DatabaseClient) DescendantTags(name string) ([]string, error) {
+	var names []string
+	name = NormalizeTag(name)
+	err := c.DB.Model(&Tag{}).
+		Where("tag_name LIKE ? ESCAPE '\\'", escapeLike(name)+TagSeparator+"%").
+		Order("tag_name ASC").
+		Pluck("tag_name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descendants of %s: %v", name, err)
+	}
+	return names, nil
+}
+
+// Rename a tag along with every tag nested below it.
+// When a new name already exists the two tags are merged.
+// Returns the number of tags renamed.
+func (c *DatabaseClient) RenameTag(oldName string, newName string) (int, error) {
+	oldName, newName = NormalizeTag(oldName), NormalizeTag(newName)
+	if newName == "" {
+		return 0, fmt.Errorf("new tag name is empty")
+	}
+	if oldName == newName {
+		return 0, nil
+	}
+	if strings.HasPrefix(newName, oldName+TagSeparator) {
+		return 0, fmt.Errorf("cannot move %s inside itself", oldName)
+	}
+
+	var tags []Tag
+	err := c.DB.Where("tag_name = ? OR tag_name LIKE ? ESCAPE '\\'", oldName, escapeLike(oldName)+TagSeparator+"%").
+		Find(&tags).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find tag %s: %v", oldName, err)
+	}
+	if len(tags) == 0 {
+		return 0, fmt.Errorf("tag %s does not exist", oldName)
+	}
+
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		for _, tag := range tags {
+			target := newName + strings.TrimPrefix(tag.TagName, oldName)
+			if err := renameOrMergeTag(tx, tag, target); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename %s: %v", oldName, err)
+	}
+	return len(tags), nil
+}
+
+func renameOrMergeTag(tx *gorm.DB, tag Tag, target string) error {
+	var existing []Tag
+	if err := tx.Where("tag_name = ?", target).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return tx.Model(&tag).Update("tag_name", target).Error
+	}
+	return mergeTagInto(tx, tag.ID, existing[0].ID)
+}
+
+// Move every entry and alias from one tag onto another and drop the old tag
+func mergeTagInto(tx *gorm.DB, fromID uint, toID uint) error {
+	// Entries that already have both tags keep a single link
+	if err := tx.Exec("UPDATE OR IGNORE entry_tags SET tag_id = ? WHERE tag_id = ?", toID, fromID).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM entry_tags WHERE tag_id = ?", fromID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&TagAlias{}).Where("tag_id = ?", fromID).Update("tag_id", toID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&Tag{}, fromID).Error
+}
+
+// Normalize names and replace aliases with their canonical tags, dropping duplicates
+func (c *DatabaseClient) ResolveAliases(names []string) []string {
+	var resolved []string
+	for _, name := range names {
+		name = NormalizeTag(name)
+		if name == "" {
+			continue
+		}
+		var aliases []TagAlias
+		c.DB.Preload("Tag").Where("alias = ?", name).Limit(1).Find(&aliases)
+		if len(aliases) > 0 {
+			name = aliases[0].Tag.TagName
+		}
+		if !utils.SliceStrContains(resolved, name) {
+			resolved = append(resolved, name)
+		}
+	}
+	return resolved
+}
+
+func (c *DatabaseClient) Aliases() ([]TagAlias, error) {
+	var aliases []TagAlias
+	err := c.DB.Preload("Tag").Joins("JOIN tags ON tags.id = tag_aliases.tag_id").
+		Order("tags.tag_name ASC, tag_aliases.alias ASC").
+		Find(&aliases).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aliases: %v", err)
+	}
+	return aliases, nil
+}
+
+// Make alias resolve to tag. Entries already tagged with the alias
+// are moved onto the canonical tag.
+func (c *DatabaseClient) AddAlias(alias string, tagName string) error {
+	alias = NormalizeTag(alias)
+	resolved := c.ResolveAliases([]string{tagName})
+	if alias == "" || len(resolved) == 0 {
+		return fmt.Errorf("alias and tag cannot be empty")
+	}
+	tagName = resolved[0]
+	if alias == tagName {
+		return fmt.Errorf("%s cannot be an alias of itself", alias)
+	}
+
+	var existing int64
+	c.DB.Model(&TagAlias{}).Where("alias = ?", alias).Count(&existing)
+	if existing > 0 {
+		return fmt.Errorf("%s is already an alias", alias)
+	}
+
+	canonical := c.batchInsertTags([]string{tagName})
+	if len(canonical) == 0 {
+		return fmt.Errorf("failed to create tag %s", tagName)
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		var tags []Tag
+		if err := tx.Where("tag_name = ?", alias).Limit(1).Find(&tags).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := mergeTagInto(tx, tags[0].ID, canonical[0].ID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&TagAlias{Alias: alias, TagID: canonical[0].ID}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add alias %s: %v", alias, err)
+	}
+	return nil
+}
+
+func (c *DatabaseClient) RemoveAlias(alias string) error {
+	alias = NormalizeTag(alias)
+	result := c.DB.Where("alias = ?", alias).Delete(&TagAlias{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove alias %s: %v", alias, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%s is not an alias", alias)
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
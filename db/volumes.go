@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Flag existing volumes, rename any that share a name and
+// make volume names unique regardless of case. The newest volume
+// keeps the name, as its file is the one written last.
+func (c *DatabaseClient) uniqueVolumeNames() error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE entries SET is_volume = true WHERE id IN (SELECT original_entry_id FROM entry_originals)").Error
+		if err != nil {
+			return err
+		}
+
+		var volumes []Entry
+		if err := tx.Where("is_volume").Order("created_at DESC, id DESC").Find(&volumes).Error; err != nil {
+			return err
+		}
+		taken := make(map[string]bool)
+		for _, volume := range volumes {
+			name := volume.Name
+			for n := 2; taken[strings.ToLower(name)]; n++ {
+				name = fmt.Sprintf("%s (%d)", volume.Name, n)
+			}
+			taken[strings.ToLower(name)] = true
+			if name != volume.Name {
+				if err := tx.Model(&volume).UpdateColumn("name", name).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_volume_name ON entries(name COLLATE NOCASE) WHERE is_volume").Error
+	})
+}
+
+// Get the volumes whose file was overwritten by a newer volume, left over
+// from before volume names were unique. The newest volume keeps the file.
+func (c *DatabaseClient) VolumesSharingFiles() ([]Entry, error) {
+	var volumes []Entry
+	err := c.DB.Where(`is_volume AND EXISTS (
+		SELECT 1 FROM entries newer WHERE newer.is_volume AND newer.file_path = entries.file_path
+		AND (newer.created_at > entries.created_at OR (newer.created_at = entries.created_at AND newer.id > entries.id)))`).
+		Order("created_at ASC, id ASC").
+		Find(&volumes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes: %v", err)
+	}
+	return volumes, nil
+}
+
+// Check whether any entry or volume is saved at a path
+func (c *DatabaseClient) FileInUse(filepath string) bool {
+	var count int64
+	c.DB.Model(&Entry{}).Where("file_path = ?", filepath).Count(&count)
+	return count > 0
+}
+
+// Point an entry at a different file
+func (c *DatabaseClient) SetFilePath(entry *Entry, filepath string) error {
+	if err := c.DB.Model(entry).UpdateColumn("file_path", filepath).Error; err != nil {
+		return fmt.Errorf("failed to update file path: %v", err)
+	}
+	return nil
+}
+
+// Find the volume with a name, ignoring case, or saved at a path.
+// Returns nil when there is none.
+func (c *DatabaseClient) FindVolume(name string, filepath string) (*Entry, error) {
+	var volumes []Entry
+	err := c.DB.Preload("Tags").Preload("Originals").
+		Where("is_volume AND (name = ? COLLATE NOCASE OR file_path = ?)", name, filepath).
+		Limit(1).
+		Find(&volumes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find volume %s: %v", name, err)
+	}
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+	return &volumes[0], nil
+}
+
+// Store how a volume is grouped so rebuilding it keeps its sections
+func (c *DatabaseClient) SetVolumeGroupBy(volume *Entry, groupBy string) error {
+	if err := c.DB.Model(volume).UpdateColumn("group_by", groupBy).Error; err != nil {
+		return fmt.Errorf("failed to update volume grouping: %v", err)
+	}
+	return nil
+}
+
+// Swap a volume's tags and originals for new ones
+func (c *DatabaseClient) ReplaceVolume(volume *Entry, tags []string, originals []Entry) error {
+	newTags := c.batchInsertTags(tags)
+	if err := c.DB.Model(volume).Association("Tags").Replace(newTags); err != nil {
+		return fmt.Errorf("failed to replace volume tags: %v", err)
+	}
+	if err := c.DB.Model(volume).Association("Originals").Replace(originals); err != nil {
+		return fmt.Errorf("failed to replace volume originals: %v", err)
+	}
+	return nil
+}
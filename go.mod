@@ -1,7 +1,9 @@
 module github.com/projectz-ro/journalz-ro
 
 require (
+	github.com/chzyer/readline v1.5.1
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/text v0.14.0
 	gorm.io/driver/sqlite v1.5.6
 	gorm.io/gorm v1.25.12
 )
@@ -12,7 +14,7 @@ require (
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/mattn/go-sqlite3 v1.14.24 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 )
 
 go 1.23.1
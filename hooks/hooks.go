@@ -0,0 +1,109 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectz-ro/journalz-ro/config"
+	"github.com/projectz-ro/journalz-ro/db"
+	utils "github.com/projectz-ro/journalz-ro/zro_utils"
+)
+
+// Journal events hooks can be attached to in the HOOKS config
+const (
+	PostNew    = "post-new"
+	PostEdit   = "post-edit"
+	PreDelete  = "pre-delete"
+	PostDelete = "post-delete"
+	PostMerge  = "post-merge"
+)
+
+// What a hook receives as JSON on stdin
+type Payload struct {
+	Event     string   `json:"event"`
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	Title     string   `json:"title"`
+	Path      string   `json:"path"`
+	Tags      []string `json:"tags"`
+	Originals []uint   `json:"originals,omitempty"`
+}
+
+func NewPayload(event string, entry *db.Entry) Payload {
+	payload := Payload{
+		Event: event,
+		ID:    entry.ID,
+		Name:  entry.Name,
+		Title: entry.DisplayTitle(),
+		Path:  entry.FilePath,
+		Tags:  []string{},
+	}
+	for _, tag := range entry.Tags {
+		payload.Tags = append(payload.Tags, tag.TagName)
+	}
+	for _, original := range entry.Originals {
+		payload.Originals = append(payload.Originals, original.ID)
+	}
+	return payload
+}
+
+// Run every hook configured for an event on an entry, stopping at the first failure.
+// A failing pre-* hook means the operation should be aborted.
+func Run(event string, entry *db.Entry) error {
+	return RunPayload(NewPayload(event, entry))
+}
+
+func RunPayload(payload Payload) error {
+	commands := config.CONFIG.HOOKS[payload.Event]
+	if len(commands) == 0 {
+		return nil
+	}
+
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %v", err)
+	}
+
+	env := append(os.Environ(),
+		"JOURNALZ_EVENT="+payload.Event,
+		"JOURNALZ_ENTRY_ID="+strconv.FormatUint(uint64(payload.ID), 10),
+		"JOURNALZ_ENTRY_NAME="+payload.Name,
+		"JOURNALZ_ENTRY_TITLE="+payload.Title,
+		"JOURNALZ_ENTRY_PATH="+payload.Path,
+		"JOURNALZ_ENTRY_TAGS="+strings.Join(payload.Tags, ","),
+	)
+
+	for _, command := range commands {
+		cmd := exec.Command(hookPath(command))
+		cmd.Env = env
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %s failed: %v", payload.Event, command, err)
+		}
+	}
+	return nil
+}
+
+// Resolve a configured hook to an executable.
+// Bare names are looked up in the config hooks dir before $PATH.
+func hookPath(command string) string {
+	if strings.HasPrefix(command, "~/") {
+		return filepath.Join(os.Getenv("HOME"), command[2:])
+	}
+	if !strings.Contains(command, "/") {
+		local := filepath.Join(config.ConfigDir, "hooks", command)
+		if utils.PathExists(local) {
+			return local
+		}
+	}
+	return command
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeadingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBulletRegex   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdNumberRegex   = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	mdTaskRegex     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	mdQuoteRegex    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdRuleRegex     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdCommentRegex  = regexp.MustCompile(`^\s*<!--.*-->\s*$`)
+	mdCodeSpanRegex = regexp.MustCompile("`[^`]+`")
+	// Images, [[wiki links]] and [links](url), matched in one pass
+	mdLinkRegex   = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)[^)]*\)|\[\[([^\[\]]+)\]\]`)
+	mdBoldRegex   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalicRegex = regexp.MustCompile(`\*([^*\s][^*]*)\*|(?:^|\b)_([^_\s][^_]*)_(?:\b|$)`)
+	mdStrikeRegex = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// Render markdown lines with colors for the terminal. Fences, heading
+// hashes and HTML comments are dropped; rules are drawn width wide.
+func RenderMarkdown(lines []string, width int) []string {
+	var out []string
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, "    "+Yellow+line+Reset)
+			continue
+		}
+
+		switch {
+		case mdCommentRegex.MatchString(line):
+			continue
+		case mdRuleRegex.MatchString(line):
+			out = append(out, BrightBlack+strings.Repeat("─", width)+Reset)
+		case mdHeadingRegex.MatchString(line):
+			match := mdHeadingRegex.FindStringSubmatch(line)
+			style := headingStyle(len(match[1]))
+			out = append(out, style+renderInline(match[2], style)+Reset)
+		case mdQuoteRegex.MatchString(line):
+			text := mdQuoteRegex.FindStringSubmatch(line)[1]
+			out = append(out, BrightBlack+"│ "+Reset+Italic+renderInline(text, Italic)+Reset)
+		case mdBulletRegex.MatchString(line):
+			match := mdBulletRegex.FindStringSubmatch(line)
+			marker, text := "•", match[2]
+			if task := mdTaskRegex.FindStringSubmatch(text); task != nil {
+				marker, text = "☐", task[2]
+				if task[1] != " " {
+					marker = "☑"
+				}
+			}
+			out = append(out, match[1]+Magenta+marker+Reset+" "+renderInline(text, ""))
+		case mdNumberRegex.MatchString(line):
+			match := mdNumberRegex.FindStringSubmatch(line)
+			out = append(out, match[1]+Magenta+match[2]+Reset+" "+renderInline(match[3], ""))
+		default:
+			out = append(out, renderInline(line, ""))
+		}
+	}
+	return out
+}
+
+func headingStyle(level int) string {
+	switch level {
+	case 1:
+		return Bold + Underline + BrightBlue
+	case 2:
+		return Bold + Blue
+	default:
+		return Bold + Cyan
+	}
+}
+
+// Style code spans, links and emphasis in a line.
+// base is the style to go back to after each span.
+func renderInline(text string, base string) string {
+	reset := Reset + base
+	var sb strings.Builder
+	last := 0
+	// Code spans are left alone apart from their color
+	for _, loc := range mdCodeSpanRegex.FindAllStringIndex(text, -1) {
+		sb.WriteString(renderSpans(text[last:loc[0]], reset))
+		sb.WriteString(Yellow + strings.Trim(text[loc[0]:loc[1]], "`") + reset)
+		last = loc[1]
+	}
+	sb.WriteString(renderSpans(text[last:], reset))
+	return sb.String()
+}
+
+func renderSpans(text string, reset string) string {
+	text = mdLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mdLinkRegex.FindStringSubmatch(match)
+		switch {
+		case parts[4] != "":
+			return Underline + Cyan + parts[4] + reset
+		case parts[1] == "!":
+			return Magenta + "[image: " + parts[2] + "]" + reset + BrightBlack + " (" + parts[3] + ")" + reset
+		default:
+			return Underline + BrightBlue + parts[2] + reset + BrightBlack + " (" + parts[3] + ")" + reset
+		}
+	})
+	text = mdBoldRegex.ReplaceAllString(text, Bold+"$1$2"+reset)
+	text = mdItalicRegex.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mdItalicRegex.FindStringSubmatch(match)
+		return Italic + parts[1] + parts[2] + reset
+	})
+	return mdStrikeRegex.ReplaceAllString(text, StrikeThrough+"$1"+reset)
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderInline(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "just text", "just text"},
+		{"bold", "a **big** deal", "a " + Bold + "big" + Reset + " deal"},
+		{"bold underscores", "a __big__ deal", "a " + Bold + "big" + Reset + " deal"},
+		{"italic", "an *odd* one", "an " + Italic + "odd" + Reset + " one"},
+		{"italic underscores", "an _odd_ one", "an " + Italic + "odd" + Reset + " one"},
+		{"snake case", "snake_case_name", "snake_case_name"},
+		{"strike", "~~gone~~", StrikeThrough + "gone" + Reset},
+		{"bold and italic", "**b** and *i*", Bold + "b" + Reset + " and " + Italic + "i" + Reset},
+		{"link", "see [docs](https://example.com)", "see " + Underline + BrightBlue + "docs" + Reset + BrightBlack + " (https://example.com)" + Reset},
+		{"link with title", `[docs](https://example.com "Docs")`, Underline + BrightBlue + "docs" + Reset + BrightBlack + " (https://example.com)" + Reset},
+		{"image", "![cat](cat.png)", Magenta + "[image: cat]" + Reset + BrightBlack + " (cat.png)" + Reset},
+		{"wiki link", "see [[Other Entry]]", "see " + Underline + Cyan + "Other Entry" + Reset},
+		{"code span", "run `go test`", "run " + Yellow + "go test" + Reset},
+		{"code span keeps markup", "`**not bold**`", Yellow + "**not bold**" + Reset},
+		{"code span between emphasis", "*a* `b` *c*", Italic + "a" + Reset + " " + Yellow + "b" + Reset + " " + Italic + "c" + Reset},
+	}
+	for _, test := range tests {
+		if got := renderInline(test.text, ""); got != test.want {
+			t.Errorf("%s: renderInline(%q) = %q, want %q", test.name, test.text, got, test.want)
+		}
+	}
+}
+
+func TestRenderInlineBase(t *testing.T) {
+	got := renderInline("a **b** c", Italic)
+	want := "a " + Bold + "b" + Reset + Italic + " c"
+	if got != want {
+		t.Errorf("renderInline with a base style = %q, want %q", got, want)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	lines := []string{
+		"# Title",
+		"Some **bold** text",
+		"<!-- hidden -->",
+		"- [x] done",
+		"1. first",
+		"> quoted",
+		"---",
+		"```",
+		"*not italic*",
+		"```",
+	}
+	want := []string{
+		Bold + Underline + BrightBlue + "Title" + Reset,
+		"Some " + Bold + "bold" + Reset + " text",
+		Magenta + "☑" + Reset + " done",
+		Magenta + "1." + Reset + " first",
+		BrightBlack + "│ " + Reset + Italic + "quoted" + Reset,
+		BrightBlack + strings.Repeat("─", 10) + Reset,
+		"    " + Yellow + "*not italic*" + Reset,
+	}
+	if got := RenderMarkdown(lines, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderMarkdown() =\n%q\nwant\n%q", got, want)
+	}
+}
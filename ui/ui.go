@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -78,7 +79,7 @@ func displayEntries(title string, entries []db.Entry) error {
 
 	for i, entry := range entries {
 		date := entry.CreatedAt.Format("01-02-2006")
-		fmt.Println(Bold, Blue, strconv.Itoa(i+1)+") ", Reset, entry.Name, " | Created: ", date)
+		fmt.Println(Bold, Blue, strconv.Itoa(i+1)+") ", Reset, EntryMarkers(entry)+entry.DisplayTitle(), " | Created: ", date, " | #"+strconv.FormatUint(uint64(entry.ID), 10))
 		// Only preview first 10
 		if i < 10 {
 			tempLines, err := utils.GetLines(entry.FilePath)
@@ -86,7 +87,8 @@ func displayEntries(title string, entries []db.Entry) error {
 				fmt.Println("Error reading body of entry at "+entry.FilePath, err)
 				return err
 			}
-			preview := tempLines[config.CONFIG.START_POS-1 : config.CONFIG.START_POS+6]
+			start := min(config.CONFIG.START_POS-1, len(tempLines))
+			preview := tempLines[start:min(start+7, len(tempLines))]
 			if len(preview) < 1 {
 				fmt.Println("\t", "No text available for preview")
 			} else {
@@ -95,6 +97,15 @@ func displayEntries(title string, entries []db.Entry) error {
 					fmt.Println("\t", Green, line, Reset)
 				}
 			}
+
+			backlinks, err := db.USERDB.Backlinks(entry.ID)
+			if err == nil && len(backlinks) > 0 {
+				var sources []string
+				for _, source := range backlinks {
+					sources = append(sources, "#"+strconv.FormatUint(uint64(source.ID), 10)+" "+source.DisplayTitle())
+				}
+				fmt.Println("\t", BrightBlack, "Linked from: "+strings.Join(sources, ", "), Reset)
+			}
 		}
 		//Separator
 		if i < len(entries)-1 {
@@ -105,6 +116,25 @@ func displayEntries(title string, entries []db.Entry) error {
 	return nil
 }
 
+// Pinned and starred markers shown before an entry's name
+func EntryMarkers(entry db.Entry) string {
+	markers := ""
+	if entry.Pinned {
+		markers += Red + "📌" + Reset + " "
+	}
+	if entry.Starred {
+		markers += Yellow + "★" + Reset + " "
+	}
+	return markers
+}
+
+// Move pinned entries to the front, keeping the order within pinned and unpinned
+func pinnedFirst(entries []db.Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Pinned && !entries[j].Pinned
+	})
+}
+
 func sectionTitle(title string, symbol string) string {
 	titleArr := strings.Split(title, "")
 	center := 20
@@ -133,6 +163,8 @@ func Render(desiredMode DisplayMode, entriesList []db.Entry, searchTags []string
 	case MergeDisplay:
 		title = string(Blue + sectionTitle("MERGE LIST", "=") + Reset)
 	case SearchDisplay:
+		// Sorted in place so the numbers shown match the caller's slice
+		pinnedFirst(entriesList)
 		title = string(Blue + sectionTitle("SEARCH RESULTS", "=") + Reset)
 		fmt.Println(Green, "SEARCH TAGS = ", Reset, strings.Join(searchTags, ","))
 	default:
@@ -150,32 +182,50 @@ func Render(desiredMode DisplayMode, entriesList []db.Entry, searchTags []string
 		// E.g. r -i finance
 		fmt.Println(Magenta + "[R]efine current search: " + Reset + "r -[opts] [tag]...")
 		// E.g. n -a health
-		fmt.Println(Magenta + "[N]ew search: " + Reset + "n -[opts] [tag]...")
-		// E.g. a 1 4 12
-		fmt.Println(Magenta + "[A]dd entry to volume list: " + Reset + "a [number]...")
+		fmt.Println(Magenta + "[N]ew search: " + Reset + "n -[opts] [tag|@search]...")
+		// E.g. a 1-5,8 12
+		fmt.Println(Magenta + "[A]dd entries to volume list: " + Reset + "a [selection]")
 		// E.g. w
-		//TODO: uncomment and make this work
-		// fmt.Println(Magenta + "[W]hole list to volume list: " + Reset + "w")
+		fmt.Println(Magenta + "[W]hole list to volume list: " + Reset + "w")
 		// E.g. d 1 4 12
-		fmt.Println(Magenta + "[D]elete entry permanently: " + Reset + "d [number]...")
-
+		fmt.Println(Magenta + "[D]elete entries permanently: " + Reset + "d [selection]")
+		// E.g. t 1-3,7 work -draft
+		fmt.Println(Magenta + "[T]ag or untag entries: " + Reset + "t [selection] [tag] [-tag]...")
+
+		// E.g. star 2 5
+		fmt.Println(Magenta + "[Star]/[Unstar] entries: " + Reset + "star [selection] / unstar [selection]")
+		// E.g. pin 1
+		fmt.Println(Magenta + "[Pin]/[Unpin] entries to the top: " + Reset + "pin [selection] / unpin [selection]")
+		// E.g. s weekly
+		fmt.Println(Magenta + "[S]ave this search: " + Reset + "s [name]")
 		// E.g. v
 		fmt.Println(Magenta + "[V]iew current volume list: " + Reset + "v")
 		// E.g. q
 		fmt.Println(Magenta + "[Q]uit: " + Reset + "q")
-		// E.g. 31
-		fmt.Println(Magenta + "[#] Number of the file to open: " + Reset + "[number]")
+		// E.g. 31 or 2-4
+		fmt.Println(Magenta + "[#] Entries to open: " + Reset + "[selection]")
+		fmt.Println(BrightBlack + "  A selection is numbers, ranges and * for all, e.g. 1-5,8 12. !3 leaves 3 out." + Reset)
 
 	} else {
 
 		// E.g. m 2024
-		fmt.Println(Magenta + "[M]erge entries from merge list to single volume: " + Reset + "m [name]...")
+		fmt.Println(Magenta + "[M]erge entries from merge list to single volume: " + Reset + "m [--group-by tag|day|month] [name]...")
 		// E.g. d 2 12 6
-		fmt.Println(Magenta + "[D]elete entries from merge list:" + Reset + "d [number]...")
+		fmt.Println(Magenta + "[D]elete entries from merge list: " + Reset + "d [selection]")
+		// E.g. t * review
+		fmt.Println(Magenta + "[T]ag or untag entries: " + Reset + "t [selection] [tag] [-tag]...")
+		// E.g. mv 3 1
+		fmt.Println(Magenta + "[Mv] Move an entry in the merge list: " + Reset + "mv [from] [to]")
+		// E.g. sort date desc
+		fmt.Println(Magenta + "[Sort] the merge list: " + Reset + "sort date|tag|title [asc|desc]")
+		// E.g. p
+		fmt.Println(Magenta + "[P]review the volume outline: " + Reset + "p [--group-by tag|day|month]")
 		// E.g. b
 		fmt.Println(Magenta + "[B]ack to results: " + Reset + "b")
 		// E.g. q
 		fmt.Println(Magenta + "[Q]uit: " + Reset + "q")
+		// E.g. 2
+		fmt.Println(Magenta + "[#] Entries to open: " + Reset + "[selection]")
 	}
 
 	// Info
@@ -2,11 +2,15 @@ package zro_utils
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Check that a file or folder exists
@@ -90,6 +94,35 @@ func GetLines(filePath string) ([]string, error) {
 	return lines, nil
 }
 
+// Copy a file's contents to dest, replacing dest if it exists
+func CopyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Get the SHA-256 of a file's contents as a hex string
+func HashFile(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // Check if a slice of strings contains a target string
 func SliceStrContains(slice []string, target string) bool {
 	for _, s := range slice {
@@ -125,3 +158,35 @@ func OpenInNvim(filePath string, startPos int, insertMode bool) {
 		return
 	}
 }
+
+// Count whitespace separated words across lines
+func CountWords(lines []string) int {
+	count := 0
+	for _, line := range lines {
+		count += len(strings.Fields(line))
+	}
+	return count
+}
+
+// Get the number of single character edits needed to turn a into b
+func Levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}